@@ -0,0 +1,165 @@
+package puente
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Usage is the traffic aggregated for a single user since the last flush
+type Usage struct {
+	UserID   string `json:"user_id"`
+	Requests int64  `json:"requests"`
+	BytesIn  int64  `json:"bytes_in"`
+	BytesOut int64  `json:"bytes_out"`
+}
+
+// AccountingSink receives the aggregated usage on every flush
+type AccountingSink interface {
+	Flush(ctx context.Context, usage []Usage) error
+}
+
+// fanOutSink flushes the same usage to several sinks
+type fanOutSink []AccountingSink
+
+// FanOutSink returns an AccountingSink flushing to every sink, each with
+// its own copy of the usage, e.g. exact counts to an internal billing sink
+// and a NoisySink for partners. Every sink is flushed even if another
+// fails; a failed flush is retried as a whole, so sinks should tolerate
+// duplicates.
+func FanOutSink(sinks ...AccountingSink) AccountingSink {
+	return fanOutSink(sinks)
+}
+
+// Flush implements AccountingSink
+func (f fanOutSink) Flush(ctx context.Context, usage []Usage) error {
+	var errs []error
+	for _, sink := range f {
+		if err := sink.Flush(ctx, append([]Usage(nil), usage...)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Accounting aggregates request and response body bytes per user
+type Accounting struct {
+	m     *Middleware
+	sink  AccountingSink
+	mu    sync.Mutex
+	usage map[string]*Usage
+}
+
+// NewAccounting returns a byte accounting middleware flushing to sink
+func (m *Middleware) NewAccounting(sink AccountingSink) *Accounting {
+	return &Accounting{
+		m:     m,
+		sink:  sink,
+		usage: map[string]*Usage{},
+	}
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.bytes += int64(n)
+	return n, err
+}
+
+// Count middleware accounts the body bytes of every request. It must be
+// placed after the authentication middleware so the user ID is known;
+// anonymous traffic is accounted under an empty user ID.
+func (a *Accounting) Count(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+
+			wrapped := newResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			a.merge(Usage{
+				UserID:   GetUserID(r.Context()),
+				Requests: 1,
+				BytesIn:  body.bytes,
+				BytesOut: wrapped.bytes,
+			})
+		},
+	)
+}
+
+// merge adds traffic to the aggregates
+func (a *Accounting) merge(usage Usage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, ok := a.usage[usage.UserID]
+	if !ok {
+		u = &Usage{UserID: usage.UserID}
+		a.usage[usage.UserID] = u
+	}
+	u.Requests += usage.Requests
+	u.BytesIn += usage.BytesIn
+	u.BytesOut += usage.BytesOut
+}
+
+// Flush sends the aggregated usage to the sink and resets the aggregates.
+// On failure the exact usage is merged back to be retried on the next
+// flush, whatever the sink did to the copy it was given.
+func (a *Accounting) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	usage := make([]Usage, 0, len(a.usage))
+	for _, u := range a.usage {
+		usage = append(usage, *u)
+	}
+	a.usage = map[string]*Usage{}
+	a.mu.Unlock()
+
+	if len(usage) == 0 {
+		return nil
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].UserID < usage[j].UserID })
+
+	if err := a.sink.Flush(ctx, append([]Usage(nil), usage...)); err != nil {
+		for _, u := range usage {
+			a.merge(u)
+		}
+		a.m.log(ErrorLevel, "Failed to flush accounting", Fields{
+			"app":   a.m.app,
+			"users": len(usage),
+			"error": err,
+		})
+		return err
+	}
+
+	return nil
+}
+
+// Run flushes the aggregates every interval until ctx is done, flushing a
+// last time before returning
+func (a *Accounting) Run(ctx context.Context, interval time.Duration) {
+	timer := a.m.clock.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C():
+			a.Flush(ctx)
+			timer.Reset(interval)
+		case <-ctx.Done():
+			a.Flush(context.Background())
+			return
+		}
+	}
+}
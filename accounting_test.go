@@ -0,0 +1,108 @@
+package puente_test
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+// usageSink records the flushed usage, failing the first fail flushes
+type usageSink struct {
+	mu      sync.Mutex
+	fail    int
+	flushes [][]puente.Usage
+}
+
+func (s *usageSink) Flush(ctx context.Context, usage []puente.Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail > 0 {
+		s.fail--
+		return errors.New("sink down")
+	}
+	s.flushes = append(s.flushes, usage)
+	return nil
+}
+
+// serve sends n requests of user with a three byte body through acct
+func serve(acct *puente.Accounting, user string, n int) {
+	h := acct.Count(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body.Read(make([]byte, 8))
+		w.Write([]byte("ok"))
+	}))
+	for i := 0; i < n; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("abc"))
+		r = r.WithContext(context.WithValue(r.Context(), puente.UserIDKey, user))
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
+
+func TestAccountingFanOutKeepsExactCounts(t *testing.T) {
+	internal, external := &usageSink{}, &usageSink{}
+	m := puente.NewWithLogger("app", &puentetest.Logger{})
+	acct := m.NewAccounting(puente.FanOutSink(internal, puente.NoisySink(external, puente.NoiseConfig{
+		Epsilon: 0.01,
+		Rand:    rand.New(rand.NewPCG(1, 2)),
+	})))
+
+	serve(acct, "alice", 5)
+	if err := acct.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := puente.Usage{UserID: "alice", Requests: 5, BytesIn: 15, BytesOut: 10}
+	if len(internal.flushes) != 1 || len(internal.flushes[0]) != 1 || internal.flushes[0][0] != want {
+		t.Errorf("internal sink got %v, want exact %v", internal.flushes, want)
+	}
+	if len(external.flushes) != 1 || external.flushes[0][0] == want {
+		t.Errorf("external sink got %v, want noised counts", external.flushes)
+	}
+}
+
+func TestAccountingMergesBackRawCounts(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func(inner puente.AccountingSink) puente.AccountingSink
+	}{
+		{"noisy", func(inner puente.AccountingSink) puente.AccountingSink {
+			return puente.NoisySink(inner, puente.NoiseConfig{Round: 10})
+		}},
+		{"fan-out", func(inner puente.AccountingSink) puente.AccountingSink {
+			return puente.FanOutSink(&usageSink{}, puente.NoisySink(inner, puente.NoiseConfig{Round: 10}))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &usageSink{fail: 1}
+			m := puente.NewWithLogger("app", &puentetest.Logger{})
+			acct := m.NewAccounting(tt.sink(inner))
+
+			// 3 requests round to 0, so merging back the rounded counts would
+			// lose them, while 3 raw plus 3 new ones round to 10
+			serve(acct, "alice", 3)
+			if err := acct.Flush(context.Background()); err == nil {
+				t.Fatal("Flush = nil, want the sink error")
+			}
+			serve(acct, "alice", 3)
+			if err := acct.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(inner.flushes) != 1 {
+				t.Fatalf("sink flushed %d times, want 1", len(inner.flushes))
+			}
+			if got := inner.flushes[0][0].Requests; got != 10 {
+				t.Errorf("requests = %d, want 6 raw requests rounded to 10", got)
+			}
+		})
+	}
+}
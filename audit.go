@@ -0,0 +1,170 @@
+package puente
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAuditBatch    = 100
+	defaultAuditInterval = time.Second
+	defaultAuditAttempts = 3
+	defaultAuditBackoff  = 100 * time.Millisecond
+)
+
+// AuditSink ships batches of security events to durable storage such as
+// Kafka or SQS. A failed batch is retried as a whole, so Write should be
+// idempotent or the sink tolerant of duplicates.
+type AuditSink interface {
+	Write(ctx context.Context, events []SecurityEvent) error
+}
+
+// AuditConfig configures an Auditor
+type AuditConfig struct {
+	// BatchSize flushes a batch once it holds this many events, 100 by
+	// default
+	BatchSize int
+	// FlushInterval flushes a partial batch after this long, one second
+	// by default
+	FlushInterval time.Duration
+	// MaxAttempts before a batch is dropped, 3 by default
+	MaxAttempts int
+	// Backoff is the initial retry delay, doubled after every attempt
+	Backoff time.Duration
+	// Types selects the audited event types, every type when empty
+	Types []SecurityEventType
+}
+
+// Auditor ships the security events published by the middleware to an
+// AuditSink
+type Auditor struct {
+	m    *Middleware
+	sink AuditSink
+	cfg  AuditConfig
+}
+
+// NewAuditor returns an Auditor writing to sink
+func (m *Middleware) NewAuditor(sink AuditSink, cfg AuditConfig) *Auditor {
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = defaultAuditBatch
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = defaultAuditInterval
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = defaultAuditAttempts
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = defaultAuditBackoff
+	}
+
+	return &Auditor{m: m, sink: sink, cfg: cfg}
+}
+
+// Run subscribes to the security events and ships them in batches until
+// ctx is done, flushing the last batch before returning
+func (a *Auditor) Run(ctx context.Context) {
+	events := a.m.Subscribe(a.cfg.Types...)
+	defer a.m.Unsubscribe(events)
+
+	timer := a.m.clock.NewTimer(a.cfg.FlushInterval)
+	defer timer.Stop()
+
+	batch := make([]SecurityEvent, 0, a.cfg.BatchSize)
+	for {
+		select {
+		case event := <-events:
+			if batch = append(batch, event); len(batch) < a.cfg.BatchSize {
+				continue
+			}
+		case <-timer.C():
+			timer.Reset(a.cfg.FlushInterval)
+			if len(batch) == 0 {
+				continue
+			}
+		case <-ctx.Done():
+			for drained := false; !drained; {
+				select {
+				case event := <-events:
+					batch = append(batch, event)
+				default:
+					drained = true
+				}
+			}
+			if len(batch) > 0 {
+				a.write(context.Background(), batch)
+			}
+			return
+		}
+
+		a.write(ctx, batch)
+		batch = make([]SecurityEvent, 0, a.cfg.BatchSize)
+	}
+}
+
+// write ships a batch with exponential backoff, dropping it after the
+// last attempt
+func (a *Auditor) write(ctx context.Context, batch []SecurityEvent) {
+	backoff := a.cfg.Backoff
+
+	for attempt := 1; ; attempt++ {
+		err := a.sink.Write(ctx, batch)
+		if err == nil {
+			return
+		}
+
+		fields := Fields{
+			"app":      a.m.app,
+			"events":   len(batch),
+			"attempts": attempt,
+			"error":    err,
+		}
+		if attempt >= a.cfg.MaxAttempts {
+			a.m.log(ErrorLevel, "Audit batch dropped", fields)
+			return
+		}
+		a.m.log(WarnLevel, "Audit batch failed", fields)
+
+		timer := a.m.clock.NewTimer(backoff)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			a.m.log(ErrorLevel, "Audit batch dropped", fields)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// writerAuditSink writes events as JSON lines
+type writerAuditSink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewWriterAuditSink returns an AuditSink writing every event as a JSON
+// line to out
+func NewWriterAuditSink(out io.Writer) AuditSink {
+	return &writerAuditSink{out: out}
+}
+
+// Write implements AuditSink
+func (s *writerAuditSink) Write(ctx context.Context, events []SecurityEvent) error {
+	var b []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		b = append(append(b, line...), '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.out.Write(b)
+	return err
+}
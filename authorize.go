@@ -0,0 +1,101 @@
+package puente
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// PolicyInput is the document a PolicyEngine decides on. Credential headers
+// (Authorization, Cookie, Set-Cookie and Proxy-Authorization, which the
+// header log always redacts) are left out.
+type PolicyInput struct {
+	Method  string            `json:"method"`
+	Path    []string          `json:"path"`
+	UserID  string            `json:"user_id,omitempty"`
+	Headers map[string]string `json:"headers"`
+}
+
+// PolicyEngine evaluates authorization policies. An OPA adapter only needs
+// to evaluate a prepared query with the input:
+//
+//	type opaEngine struct{ query rego.PreparedEvalQuery }
+//
+//	func (e opaEngine) Evaluate(ctx context.Context, in puente.PolicyInput) (bool, error) {
+//		rs, err := e.query.Eval(ctx, rego.EvalInput(in))
+//		if err != nil {
+//			return false, err
+//		}
+//		return rs.Allowed(), nil
+//	}
+type PolicyEngine interface {
+	Evaluate(ctx context.Context, input PolicyInput) (bool, error)
+}
+
+// Authorize middleware asks engine for a decision on every request,
+// replying 403 when denied. It must be placed after the authentication
+// middleware so the user ID is part of the input.
+func (m *Middleware) Authorize(engine PolicyEngine) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				r, requestID := m.withRequestID(r)
+				input := newPolicyInput(r)
+
+				allowed, err := engine.Evaluate(r.Context(), input)
+
+				fields := Fields{
+					"app":        m.app,
+					"request_id": requestID,
+					"method":     r.Method,
+					"path":       r.URL.EscapedPath(),
+					"user_id":    input.UserID,
+				}
+				if err != nil {
+					fields["error"] = err
+					m.logRequest(r.Context(), ErrorLevel, "Policy evaluation failed", fields)
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				if !allowed {
+					m.logRequest(r.Context(), WarnLevel, "Authorization denied", fields)
+					m.publishSecurity(EventAccessDenied, r, requestID, "denied by policy")
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+// credentialHeader reports whether the header is one of the always
+// redacted credential headers
+func credentialHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	for _, redactedName := range defaultRedactedHeaders {
+		if name == redactedName {
+			return true
+		}
+	}
+	return false
+}
+
+// newPolicyInput builds the policy input document of a request
+func newPolicyInput(r *http.Request) PolicyInput {
+	headers := make(map[string]string, len(r.Header))
+	for k := range r.Header {
+		if credentialHeader(k) {
+			continue
+		}
+		headers[strings.ToLower(k)] = r.Header.Get(k)
+	}
+
+	return PolicyInput{
+		Method:  r.Method,
+		Path:    strings.Split(strings.Trim(r.URL.Path, "/"), "/"),
+		UserID:  GetUserID(r.Context()),
+		Headers: headers,
+	}
+}
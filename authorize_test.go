@@ -0,0 +1,53 @@
+package puente_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+// recordingPolicy allows every request, keeping the last input
+type recordingPolicy struct {
+	input puente.PolicyInput
+}
+
+func (p *recordingPolicy) Evaluate(ctx context.Context, input puente.PolicyInput) (bool, error) {
+	p.input = input
+	return true, nil
+}
+
+func TestPolicyInputHeaders(t *testing.T) {
+	tests := []struct {
+		header string
+		kept   bool
+	}{
+		{"Authorization", false},
+		{"Cookie", false},
+		{"Set-Cookie", false},
+		{"Proxy-Authorization", false},
+		{"X-Tenant", true},
+		{"Accept", true},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	for _, tt := range tests {
+		r.Header.Set(tt.header, "value")
+	}
+
+	engine := &recordingPolicy{}
+	m := puente.NewWithLogger("app", &puentetest.Logger{})
+	m.Authorize(engine)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(httptest.NewRecorder(), r)
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if _, ok := engine.input.Headers[strings.ToLower(tt.header)]; ok != tt.kept {
+				t.Errorf("header in input = %v, want %v", ok, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package puente
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrInvalidCredentials is returned when the supplied credentials are rejected
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// CredentialVerifier validates a username and password pair and returns the
+// user ID to store in the request context
+type CredentialVerifier interface {
+	Verify(ctx context.Context, username, password string) (string, error)
+}
+
+// MemoryCredentials is an in-memory CredentialVerifier keyed by username.
+// Passwords are compared in constant time.
+type MemoryCredentials struct {
+	users map[string][sha256.Size]byte
+}
+
+// NewMemoryCredentials returns a verifier for the given username/password pairs
+func NewMemoryCredentials(users map[string]string) *MemoryCredentials {
+	hashed := make(map[string][sha256.Size]byte, len(users))
+	for username, password := range users {
+		hashed[username] = sha256.Sum256([]byte(password))
+	}
+
+	return &MemoryCredentials{users: hashed}
+}
+
+// Verify implements CredentialVerifier, using the username as user ID
+func (c *MemoryCredentials) Verify(_ context.Context, username, password string) (string, error) {
+	expected, ok := c.users[username]
+	given := sha256.Sum256([]byte(password))
+
+	// Compare even for unknown users so timing does not reveal them
+	match := subtle.ConstantTimeCompare(expected[:], given[:]) == 1
+	if !ok || !match {
+		return "", ErrInvalidCredentials
+	}
+
+	return username, nil
+}
+
+// WithStripAuthorization removes the Authorization header once BasicAuth has
+// validated it, so downstream services never see end-user credentials. A
+// non-empty replacement, e.g. an internal service token, is set instead.
+func WithStripAuthorization(replacement string) Option {
+	return func(m *Middleware) {
+		m.stripAuth = true
+		m.authReplacement = replacement
+	}
+}
+
+// BasicAuth middleware authenticates requests with HTTP Basic credentials
+// checked against verifier, storing the user ID under UserIDKey
+func (m *Middleware) BasicAuth(verifier CredentialVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				r, requestID := m.withRequestID(r)
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", m.app))
+
+				username, password, ok := r.BasicAuth()
+				if !ok {
+					m.authFailure(w, r, requestID, username, errors.New("missing basic auth credentials"))
+					return
+				}
+
+				userID, err := verifier.Verify(r.Context(), username, password)
+				if err != nil {
+					m.authFailure(w, r, requestID, username, err)
+					return
+				}
+
+				w.Header().Del("WWW-Authenticate")
+				SetLogField(r.Context(), "user_id", userID)
+				ctx := context.WithValue(r.Context(), UserIDKey, userID)
+				r = r.WithContext(ctx)
+
+				if m.stripAuth {
+					r.Header = r.Header.Clone()
+					r.Header.Del("Authorization")
+					if m.authReplacement != "" {
+						r.Header.Set("Authorization", m.authReplacement)
+					}
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+// authFailure logs a rejected authentication attempt and replies with 401
+func (m *Middleware) authFailure(w http.ResponseWriter, r *http.Request, requestID, username string, err error) {
+	fields := Fields{
+		"app":        m.app,
+		"request_id": requestID,
+		"method":     r.Method,
+		"path":       r.URL.EscapedPath(),
+		"error":      err,
+	}
+	if username != "" {
+		fields["username"] = username
+	}
+
+	m.logRequest(r.Context(), WarnLevel, "Authentication failed", fields)
+	m.publishSecurity(EventAuthFailure, r, requestID, err.Error())
+
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
@@ -0,0 +1,63 @@
+package puente_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestBasicAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		auth      func(r *http.Request)
+		status    int
+		challenge string
+		userID    string
+	}{
+		{"valid", func(r *http.Request) { r.SetBasicAuth("alice", "s3cr3t") }, http.StatusOK, "", "alice"},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("alice", "guess") }, http.StatusUnauthorized, `Basic realm="app"`, ""},
+		{"unknown user", func(r *http.Request) { r.SetBasicAuth("mallory", "s3cr3t") }, http.StatusUnauthorized, `Basic realm="app"`, ""},
+		{"missing", func(r *http.Request) {}, http.StatusUnauthorized, `Basic realm="app"`, ""},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusUnauthorized, `Basic realm="app"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &puentetest.Logger{}
+			m := puente.NewWithLogger("app", logger, puente.WithStripAuthorization(""))
+			events := m.Subscribe(puente.EventAuthFailure)
+			defer m.Unsubscribe(events)
+
+			verifier := puente.NewMemoryCredentials(map[string]string{"alice": "s3cr3t"})
+			var userID string
+			h := m.BasicAuth(verifier)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID = puente.GetUserID(r.Context())
+				if r.Header.Get("Authorization") != "" {
+					t.Error("Authorization header passed on")
+				}
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.auth(r)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.challenge)
+			}
+			if userID != tt.userID {
+				t.Errorf("user ID = %q, want %q", userID, tt.userID)
+			}
+
+			rejected := tt.status == http.StatusUnauthorized
+			if got := len(events) == 1; got != rejected {
+				t.Errorf("auth failure published = %v, want %v", got, rejected)
+			}
+		})
+	}
+}
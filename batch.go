@@ -0,0 +1,177 @@
+package puente
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	// maxBatchSize limits the number of sub-requests accepted in a single batch
+	maxBatchSize = 100
+	// maxBatchBody limits the size of a batch payload
+	maxBatchBody = 4 << 20
+)
+
+// BatchRequest describes a single sub-request of a batch
+type BatchRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse holds the outcome of a single sub-request, with every value
+// of its response headers
+type BatchResponse struct {
+	RequestID string          `json:"request_id"`
+	Status    int             `json:"status"`
+	Headers   http.Header     `json:"headers,omitempty"`
+	Body      json.RawMessage `json:"body,omitempty"`
+}
+
+// recorder buffers a sub-request response within the memory budget
+type recorder struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+	budget     *memoryBudget
+	skipped    bool
+}
+
+func newRecorder(budget *memoryBudget) *recorder {
+	return &recorder{header: http.Header{}, statusCode: http.StatusOK, budget: budget}
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.skipped || !rec.budget.acquire(int64(len(b))) {
+		rec.skipped = true
+		return len(b), nil
+	}
+	return rec.body.Write(b)
+}
+
+func (rec *recorder) WriteHeader(code int) {
+	rec.statusCode = code
+}
+
+// Batch returns a handler that decodes a JSON array of sub-requests and
+// executes each of them through next, which should be the full middleware
+// chain. Every sub-request inherits the parent headers (including
+// credentials) and receives a child request ID derived from the parent one.
+// Payloads are limited to 4 MiB, held from the memory budget while decoded.
+func (m *Middleware) Batch(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+
+			reserve := int64(maxBatchBody)
+			if r.ContentLength >= 0 && r.ContentLength < reserve {
+				reserve = r.ContentLength
+			}
+			if !m.budget.acquire(reserve) {
+				SetLogField(r.Context(), "buffer_skipped", true)
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			defer m.budget.release(reserve)
+
+			var batch []BatchRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, reserve)).Decode(&batch); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, "batch payload too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "invalid batch payload", http.StatusBadRequest)
+				return
+			}
+			if len(batch) > maxBatchSize {
+				http.Error(w, "too many batch requests", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r, parentID := m.withRequestID(r)
+			responses := make([]BatchResponse, len(batch))
+			var buffered int64
+			for i, sub := range batch {
+				var n int64
+				responses[i], n = m.serveBatchRequest(next, r, sub, fmt.Sprintf("%s-%d", parentID, i))
+				buffered += n
+			}
+			defer m.budget.release(buffered)
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(responses)
+		},
+	)
+}
+
+// serveBatchRequest executes a single sub-request and records its response,
+// returning the bytes held from the memory budget
+func (m *Middleware) serveBatchRequest(next http.Handler, parent *http.Request, sub BatchRequest, requestID string) (BatchResponse, int64) {
+	var body io.Reader = http.NoBody
+	if len(sub.Body) > 0 {
+		body = bytes.NewReader(sub.Body)
+	}
+
+	method := sub.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	ctx := context.WithValue(parent.Context(), RequestIDKey, requestID)
+	req, err := http.NewRequestWithContext(ctx, method, sub.Path, body)
+	if err != nil {
+		return BatchResponse{RequestID: requestID, Status: http.StatusBadRequest}, 0
+	}
+	req.Header = parent.Header.Clone()
+	req.Header.Del("Content-Length")
+	for k, v := range sub.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Host = parent.Host
+	req.RemoteAddr = parent.RemoteAddr
+	req.TLS = parent.TLS
+
+	rec := newRecorder(m.budget)
+	next.ServeHTTP(rec, req)
+
+	if rec.skipped {
+		m.budget.release(int64(rec.body.Len()))
+		SetLogField(parent.Context(), "buffer_skipped", true)
+		return BatchResponse{RequestID: requestID, Status: http.StatusServiceUnavailable}, 0
+	}
+
+	headers := make(http.Header, len(rec.header))
+	for k, v := range rec.header {
+		headers[k] = append(headers[k], v...)
+	}
+
+	resp := BatchResponse{
+		RequestID: requestID,
+		Status:    rec.statusCode,
+		Headers:   headers,
+	}
+	if rec.body.Len() > 0 {
+		if json.Valid(rec.body.Bytes()) {
+			resp.Body = rec.body.Bytes()
+		} else {
+			resp.Body, _ = json.Marshal(rec.body.String())
+		}
+	}
+
+	return resp, int64(rec.body.Len())
+}
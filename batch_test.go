@@ -0,0 +1,88 @@
+package puente_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestBatchChildIDsAndBudget(t *testing.T) {
+	m := puente.NewWithLogger("app", &puentetest.Logger{}, puente.WithMemoryBudget(1<<10))
+
+	var seen []string
+	h := m.Batch(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, puente.GetRequestID(r.Context()))
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Origin")
+		switch r.URL.Path {
+		case "/large":
+			fmt.Fprintf(w, "%q", strings.Repeat("x", 2<<10))
+		default:
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+
+	payload := `[{"path":"/small"},{"path":"/large"},{"method":"POST","path":"/small","body":{"a":1}}]`
+	tests := []struct {
+		requestID string
+		status    []int
+	}{
+		{"parent", []int{http.StatusOK, http.StatusServiceUnavailable, http.StatusOK}},
+		// A leaked reservation would starve the second batch
+		{"again", []int{http.StatusOK, http.StatusServiceUnavailable, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.requestID, func(t *testing.T) {
+			seen = nil
+			r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(payload))
+			r.Header.Set("X-Request-ID", tt.requestID)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			var responses []puente.BatchResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &responses); err != nil {
+				t.Fatal(err)
+			}
+			if len(responses) != len(tt.status) {
+				t.Fatalf("got %d responses, want %d", len(responses), len(tt.status))
+			}
+
+			for i, resp := range responses {
+				want := fmt.Sprintf("%s-%d", tt.requestID, i)
+				if resp.RequestID != want || seen[i] != want {
+					t.Errorf("response %d request ID = %q, handler saw %q, want %q", i, resp.RequestID, seen[i], want)
+				}
+				if resp.Status != tt.status[i] {
+					t.Errorf("response %d status = %d, want %d", i, resp.Status, tt.status[i])
+				}
+				if resp.Status == http.StatusOK && len(resp.Headers.Values("Vary")) != 2 {
+					t.Errorf("response %d Vary = %v, want both values", i, resp.Headers.Values("Vary"))
+				}
+			}
+		})
+	}
+}
+
+func TestBatchPayloadOverBudget(t *testing.T) {
+	m := puente.NewWithLogger("app", &puentetest.Logger{}, puente.WithMemoryBudget(64))
+	h := m.Batch(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("sub-request served")
+	}))
+
+	payload := `[` + strings.Repeat(`{"path":"/small"},`, 10) + `{"path":"/small"}]`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(payload)))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
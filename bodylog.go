@@ -0,0 +1,120 @@
+package puente
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const defaultBodyLogBytes = 4096
+
+// BodyLogConfig configures request and response body capture
+type BodyLogConfig struct {
+	// MaxBytes captured per body, 4 KiB by default
+	MaxBytes int
+	// ContentTypes eligible for capture, matched by prefix. Defaults to
+	// text/ and application/json; application/*+json is always eligible.
+	ContentTypes []string
+	// Enabled selects the routes bodies are captured for, all by default
+	Enabled func(r *http.Request) bool
+}
+
+// WithBodyLogging adds up to MaxBytes of the textual request and response
+// bodies to the access log as request_body and response_body. Intended for
+// debugging: bodies may hold sensitive data.
+func WithBodyLogging(cfg BodyLogConfig) Option {
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = defaultBodyLogBytes
+	}
+	if cfg.ContentTypes == nil {
+		cfg.ContentTypes = []string{"text/", "application/json"}
+	}
+
+	return func(m *Middleware) {
+		m.bodyLog = &cfg
+	}
+}
+
+// textual reports whether contentType may be captured
+func (cfg *BodyLogConfig) textual(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json") {
+		return true
+	}
+	for _, prefix := range cfg.ContentTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureBuffer keeps the first max bytes written to it
+type captureBuffer struct {
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+func (c *captureBuffer) Write(b []byte) (int, error) {
+	room := c.max - len(c.buf)
+	if len(b) > room {
+		c.truncated = true
+		b = b[:room]
+	}
+	c.buf = append(c.buf, b...)
+	return len(b), nil
+}
+
+// teeReadCloser captures what is read from a request body
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureBodies starts capturing the bodies of r, returning a function
+// adding them to fields and one releasing the memory reserved for them,
+// which must be called even if the handler panics. Capture is skipped
+// when the memory budget is exhausted.
+func (m *Middleware) captureBodies(r *http.Request, w *responseWriter) (add func(fields Fields), release func()) {
+	cfg := m.bodyLog
+	if cfg == nil || (cfg.Enabled != nil && !cfg.Enabled(r)) {
+		return func(Fields) {}, func() {}
+	}
+
+	reserve := int64(2 * cfg.MaxBytes)
+	if !m.budget.acquire(reserve) {
+		return func(fields Fields) {
+			fields["buffer_skipped"] = true
+		}, func() {}
+	}
+
+	var request *captureBuffer
+	if r.Body != nil && r.Body != http.NoBody && cfg.textual(r.Header.Get("Content-Type")) {
+		request = &captureBuffer{max: cfg.MaxBytes}
+		r.Body = teeReadCloser{io.TeeReader(r.Body, request), r.Body}
+	}
+
+	w.capture = &captureBuffer{max: cfg.MaxBytes}
+	w.textual = cfg.textual
+
+	add = func(fields Fields) {
+		if request != nil && len(request.buf) > 0 {
+			fields["request_body"] = string(request.buf)
+			if request.truncated {
+				fields["request_body_truncated"] = true
+			}
+		}
+		if w.capture != nil && len(w.capture.buf) > 0 {
+			fields["response_body"] = string(w.capture.buf)
+			if w.capture.truncated {
+				fields["response_body_truncated"] = true
+			}
+		}
+	}
+	return add, func() { m.budget.release(reserve) }
+}
@@ -0,0 +1,39 @@
+package puente
+
+import "sync/atomic"
+
+// memoryBudget bounds the memory shared by all request and response
+// buffering. A nil budget is unlimited.
+type memoryBudget struct {
+	limit int64
+	used  int64
+}
+
+// WithMemoryBudget limits the bytes buffered at once across all requests by
+// features such as Batch and VerifySignature. Once exceeded, buffering is
+// skipped and the access log gets buffer_skipped=true.
+func WithMemoryBudget(bytes int64) Option {
+	return func(m *Middleware) {
+		m.budget = &memoryBudget{limit: bytes}
+	}
+}
+
+// acquire reserves n bytes, reporting whether they fit in the budget
+func (b *memoryBudget) acquire(n int64) bool {
+	if b == nil {
+		return true
+	}
+
+	if atomic.AddInt64(&b.used, n) > b.limit {
+		atomic.AddInt64(&b.used, -n)
+		return false
+	}
+	return true
+}
+
+// release returns n previously acquired bytes
+func (b *memoryBudget) release(n int64) {
+	if b != nil {
+		atomic.AddInt64(&b.used, -n)
+	}
+}
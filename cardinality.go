@@ -0,0 +1,150 @@
+package puente
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultCardinalityWindow = time.Hour
+	// overflowValue replaces new values of a field past its distinct limit
+	overflowValue = "[other]"
+)
+
+// CardinalityRule bounds the values of a log field
+type CardinalityRule struct {
+	Field string
+	// MaxLength truncates longer values, or hashes them when Hash is set
+	MaxLength int
+	Hash      bool
+	// MaxDistinct bounds the distinct values logged per window; further
+	// new values are logged as "[other]"
+	MaxDistinct int
+}
+
+// CardinalityConfig configures the cardinality guard
+type CardinalityConfig struct {
+	Rules []CardinalityRule
+	// Window resets the distinct values seen, one hour by default
+	Window time.Duration
+}
+
+// cardinalityGuard enforces the cardinality rules
+type cardinalityGuard struct {
+	mu       sync.Mutex
+	rules    map[string]CardinalityRule
+	window   time.Duration
+	start    time.Time
+	seen     map[string]map[string]struct{}
+	reported map[string]bool
+}
+
+// offender is a rule triggered for the first time in the window
+type offender struct {
+	field  string
+	reason string
+	limit  int
+}
+
+// WithCardinalityGuard bounds the values of the fields named by the rules,
+// protecting log and metrics backends from unbounded cardinality such as
+// raw paths with IDs or huge header values. The first offence of every
+// field per window is reported with a "High cardinality field" warning.
+func WithCardinalityGuard(cfg CardinalityConfig) Option {
+	g := &cardinalityGuard{
+		rules:    map[string]CardinalityRule{},
+		window:   cfg.Window,
+		seen:     map[string]map[string]struct{}{},
+		reported: map[string]bool{},
+	}
+	if g.window == 0 {
+		g.window = defaultCardinalityWindow
+	}
+	for _, rule := range cfg.Rules {
+		g.rules[rule.Field] = rule
+	}
+
+	return func(m *Middleware) {
+		m.cardinality = g
+	}
+}
+
+// guard bounds fields in place and reports new offenders
+func (m *Middleware) guard(fields Fields) {
+	if m.cardinality == nil {
+		return
+	}
+
+	for _, o := range m.cardinality.apply(fields, m.now()) {
+		m.emit(WarnLevel, "High cardinality field", Fields{
+			"app":    m.app,
+			"field":  o.field,
+			"reason": o.reason,
+			"limit":  o.limit,
+		})
+	}
+}
+
+// apply bounds the fields, returning the rules triggered for the first
+// time in the window
+func (g *cardinalityGuard) apply(fields Fields, now time.Time) []offender {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if now.Sub(g.start) >= g.window {
+		g.start = now
+		g.seen = map[string]map[string]struct{}{}
+		g.reported = map[string]bool{}
+	}
+
+	var offenders []offender
+	report := func(rule CardinalityRule, reason string, limit int) {
+		if key := rule.Field + " " + reason; !g.reported[key] {
+			g.reported[key] = true
+			offenders = append(offenders, offender{rule.Field, reason, limit})
+		}
+	}
+
+	for field, rule := range g.rules {
+		value, ok := fields[field].(string)
+		if !ok {
+			continue
+		}
+
+		if rule.MaxLength > 0 && len(value) > rule.MaxLength {
+			report(rule, "length", rule.MaxLength)
+			if rule.Hash {
+				sum := sha256.Sum256([]byte(value))
+				value = hex.EncodeToString(sum[:8])
+			} else {
+				n := rule.MaxLength
+				for n > 0 && !utf8.RuneStart(value[n]) {
+					n--
+				}
+				value = value[:n]
+			}
+		}
+
+		if rule.MaxDistinct > 0 {
+			seen := g.seen[field]
+			if seen == nil {
+				seen = map[string]struct{}{}
+				g.seen[field] = seen
+			}
+			if _, ok := seen[value]; !ok {
+				if len(seen) < rule.MaxDistinct {
+					seen[value] = struct{}{}
+				} else {
+					report(rule, "distinct", rule.MaxDistinct)
+					value = overflowValue
+				}
+			}
+		}
+
+		fields[field] = value
+	}
+	return offenders
+}
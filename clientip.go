@@ -0,0 +1,90 @@
+package puente
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// WithTrustedProxies sets the proxies allowed to report the client address
+// through X-Forwarded-For, Forwarded or X-Real-IP. Without trusted proxies
+// the client IP is always taken from the connection.
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(m *Middleware) {
+		for _, prefix := range prefixes {
+			addr := prefix.Addr()
+			if addr.Is4In6() && prefix.Bits() >= 96 {
+				prefix = netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96)
+			}
+			m.trustedProxies = append(m.trustedProxies, prefix.Masked())
+		}
+	}
+}
+
+// GetClientIP returns the client IP resolved by the Logging middleware
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ClientIPKey).(string)
+	return ip
+}
+
+// trusted reports whether ip belongs to a trusted proxy
+func (m *Middleware) trusted(ip netip.Addr) bool {
+	for _, prefix := range m.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP resolves the client address of r. Forwarding headers are only
+// honored when the connection comes from a trusted proxy, and are walked
+// from the nearest hop backwards, skipping trusted proxies.
+func (m *Middleware) clientIP(r *http.Request) string {
+	remote := remoteIP(r.RemoteAddr)
+	if !remote.IsValid() {
+		return r.RemoteAddr
+	}
+	if !m.trusted(remote) {
+		return remote.String()
+	}
+
+	var hops []string
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		for _, value := range xff {
+			hops = append(hops, strings.Split(value, ",")...)
+		}
+	} else if forwarded := r.Header.Values("Forwarded"); len(forwarded) > 0 {
+		hops = ParseForwardedFor(strings.Join(forwarded, ","))
+	} else if real := r.Header.Get("X-Real-IP"); real != "" {
+		hops = []string{real}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := CanonicalIP(hops[i])
+		if err != nil {
+			break
+		}
+		if !m.trusted(ip) {
+			return ip.String()
+		}
+	}
+
+	return remote.String()
+}
+
+// remoteIP parses the IP of a connection address
+func remoteIP(addr string) netip.Addr {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	ip, err := CanonicalIP(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ip
+}
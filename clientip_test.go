@@ -0,0 +1,49 @@
+package puente_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestClientIPWalksTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote string
+		header http.Header
+		want   string
+	}{
+		{"untrusted connection ignores headers", "203.0.113.7:4000", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+		{"spoofed leftmost hop", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1"}}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {"198.51.100.1, 10.0.0.3, 10.0.0.2"}}, "198.51.100.1"},
+		{"repeated headers", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1", "10.0.0.2"}}, "198.51.100.1"},
+		{"whitespace and mapped address", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {" ::ffff:198.51.100.1 ,10.0.0.2"}}, "198.51.100.1"},
+		{"invalid hop stops the walk", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {"198.51.100.1, garbage"}}, "10.0.0.1"},
+		{"only trusted hops", "10.0.0.1:4000", http.Header{"X-Forwarded-For": {"10.0.0.2"}}, "10.0.0.1"},
+		{"forwarded header", "10.0.0.1:4000", http.Header{"Forwarded": {`for="[2001:db8::1]:443", for=10.0.0.2`}}, "2001:db8::1"},
+		{"x-real-ip", "10.0.0.1:4000", http.Header{"X-Real-Ip": {"198.51.100.1"}}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := puente.NewWithLogger("app", &puentetest.Logger{}, puente.WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8")))
+
+			var got string
+			h := m.Logging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = puente.GetClientIP(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			r.Header = tt.header
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
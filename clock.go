@@ -0,0 +1,83 @@
+package puente
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time to every time-dependent feature of the middleware:
+// access log and long poll durations, slow request thresholds, signature
+// replay windows, job and event timestamps, cardinality windows, connection
+// tracking, preflight certificate validity and the server report. Its
+// timers drive long poll waits, webhook and audit retries, periodic
+// flushes, config watching and the drain delay. Loggers and the
+// FallbackWriter take their own Clock. Tests can inject a fake to advance
+// time deterministically.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a timer firing once d has elapsed on the clock
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event timer of a Clock, like time.Timer
+type Timer interface {
+	// C delivers the time the timer fired
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, reporting whether it was active
+	Stop() bool
+	// Reset changes the timer to fire after d, reporting whether it was
+	// active
+	Reset(d time.Duration) bool
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// realTimer adapts time.Timer to Timer
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// WithClock sets the clock of the middleware, the wall clock by default.
+// Connection deadlines and the systemd watchdog always use the wall clock.
+func WithClock(clock Clock) Option {
+	return func(m *Middleware) {
+		m.clock = clock
+	}
+}
+
+// now returns the current time of the middleware clock
+func (m *Middleware) now() time.Time {
+	return m.clock.Now()
+}
+
+// clockFrom returns the clock of the middleware serving ctx, the wall clock
+// outside the Logging middleware and preflight checks
+func clockFrom(ctx context.Context) Clock {
+	if m, ok := ctx.Value(loggerKey).(*Middleware); ok {
+		return m.clock
+	}
+	return realClock{}
+}
+
+// sleep waits d on clock
+func sleep(clock Clock, d time.Duration) {
+	<-clock.NewTimer(d).C()
+}
+
+// since returns the time elapsed since t on the middleware clock
+func (m *Middleware) since(t time.Time) time.Duration {
+	return m.clock.Now().Sub(t)
+}
@@ -0,0 +1,88 @@
+package puente
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// commonLogTime is the timestamp layout of the Common Log Format
+const commonLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// CommonLogConfig configures Apache Common/Combined Log Format output
+type CommonLogConfig struct {
+	// Out receives one line per request
+	Out io.Writer
+	// Combined appends the Referer and User-Agent to every line
+	Combined bool
+	// Only disables the structured access log entry
+	Only bool
+}
+
+// commonLog writes CLF lines
+type commonLog struct {
+	mu  sync.Mutex
+	cfg CommonLogConfig
+}
+
+// WithCommonLog writes a Common (or Combined) Log Format line for every
+// request, in addition to or instead of the structured access log. Lines go
+// through the scrubbers like structured entries, and the request line
+// carries the query string only with WithQueryLogging, scrubbed likewise.
+func WithCommonLog(cfg CommonLogConfig) Option {
+	return func(m *Middleware) {
+		m.commonLog = &commonLog{cfg: cfg}
+	}
+}
+
+// writeCommonLog emits the CLF line of a completed request
+func (m *Middleware) writeCommonLog(r *http.Request, host, userID string, status int, bytes int64, start time.Time) {
+	c := m.commonLog
+
+	b := make([]byte, 0, 256)
+	b = append(b, clfField(m.scrubString("client_ip", host))...)
+	b = append(b, " - "...)
+	b = append(b, clfField(m.scrubString("user_id", userID))...)
+	b = append(b, " ["...)
+	b = start.AppendFormat(b, commonLogTime)
+	b = append(b, "] "...)
+	b = strconv.AppendQuote(b, r.Method+" "+m.requestTarget(r)+" "+r.Proto)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(status), 10)
+	b = append(b, ' ')
+	if bytes > 0 {
+		b = strconv.AppendInt(b, bytes, 10)
+	} else {
+		b = append(b, '-')
+	}
+	if c.cfg.Combined {
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, clfField(m.scrubString("referer", r.Referer())))
+		b = append(b, ' ')
+		b = strconv.AppendQuote(b, clfField(m.scrubString("user_agent", r.UserAgent())))
+	}
+	b = append(b, '\n')
+
+	c.mu.Lock()
+	c.cfg.Out.Write(b)
+	c.mu.Unlock()
+}
+
+// requestTarget returns the scrubbed path and query of the request line
+func (m *Middleware) requestTarget(r *http.Request) string {
+	target := m.scrubString("path", r.URL.EscapedPath())
+	if m.queryLog != nil && r.URL.RawQuery != "" {
+		target += "?" + m.scrubString("query", m.queryLog.scrubQuery(r.URL.RawQuery))
+	}
+	return target
+}
+
+// clfField returns "-" for empty values
+func clfField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
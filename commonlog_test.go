@@ -0,0 +1,39 @@
+package puente_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestCommonLogScrubsRequestLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []puente.Option
+		target string
+		want   string
+	}{
+		{"query dropped without query logging", nil, "/users?token=s3cr3t", `"GET /users HTTP/1.1"`},
+		{"query parameters scrubbed", []puente.Option{puente.WithQueryLogging(puente.QueryLogConfig{})}, "/users?token=s3cr3t&page=2", `"GET /users?token=[REDACTED]&page=2 HTTP/1.1"`},
+		{"path scrubbed", []puente.Option{puente.WithScrubbers(puente.EmailScrubber)}, "/users/jane@example.com", `"GET /users/[REDACTED] HTTP/1.1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			opts := append(tt.opts, puente.WithCommonLog(puente.CommonLogConfig{Out: &out, Only: true}))
+			m := puente.NewWithLogger("app", &puentetest.Logger{}, opts...)
+
+			h := m.Logging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if line := out.String(); !strings.Contains(line, tt.want) {
+				t.Errorf("line = %q, want it to contain %s", line, tt.want)
+			}
+		})
+	}
+}
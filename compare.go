@@ -0,0 +1,233 @@
+package puente
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+const defaultMaxCompareBody = 1 << 20
+
+// CompareConfig runs two implementations of a middleware side by side. One
+// of them is enforced, the other only evaluated, and requests on which
+// their decisions differ are logged.
+type CompareConfig struct {
+	// Name identifies the middleware in the disagreement log
+	Name string
+	// Primary and Candidate are the implementations being compared
+	Primary   func(http.Handler) http.Handler
+	Candidate func(http.Handler) http.Handler
+	// Enabled turns the comparison on and off at runtime, always on when nil
+	Enabled func() bool
+	// EnforceCandidate switches enforcement to the candidate at runtime,
+	// leaving the primary to be evaluated instead
+	EnforceCandidate func() bool
+	// MaxBodyBytes limits the body buffered to replay the request to the
+	// evaluated implementation, 1 MiB by default. Larger requests are not
+	// compared.
+	MaxBodyBytes int64
+}
+
+// decisionWriter records the status written by a middleware
+type decisionWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (d *decisionWriter) WriteHeader(code int) {
+	if d.status == 0 {
+		d.status = code
+	}
+	d.ResponseWriter.WriteHeader(code)
+}
+
+func (d *decisionWriter) Write(b []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+	return d.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, which streaming handlers assert on the
+// writer they are given
+func (d *decisionWriter) Flush() {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+	http.NewResponseController(d.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker
+func (d *decisionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(d.ResponseWriter).Hijack()
+}
+
+func (d *decisionWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
+
+// shadowWriter discards the response of the evaluated implementation
+type shadowWriter struct {
+	header http.Header
+	status int
+}
+
+func (s *shadowWriter) Header() http.Header {
+	return s.header
+}
+
+func (s *shadowWriter) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+}
+
+func (s *shadowWriter) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+const (
+	// compareKey is the context key of the flag set when a compared
+	// implementation passes the request on
+	compareKey contextKey = "puente_compare"
+	// shadowKey marks the copy of the request given to the evaluated
+	// implementation
+	shadowKey contextKey = "puente_shadow"
+)
+
+// shadowed reports whether ctx is the context of a request evaluated by
+// Compare, whose log entries and security events are dropped
+func shadowed(ctx context.Context) bool {
+	s, _ := ctx.Value(shadowKey).(bool)
+	return s
+}
+
+// Compare middleware enforces one implementation and evaluates the other
+// against a copy of the request. A decision is either passing the request
+// on or answering it with a status; the evaluated implementation never
+// reaches the wrapped handler and runs once the enforced one is done. Its
+// response is discarded and its log fields, log entries and security
+// events are dropped.
+func (m *Middleware) Compare(cfg CompareConfig) func(http.Handler) http.Handler {
+	if cfg.MaxBodyBytes == 0 {
+		cfg.MaxBodyBytes = defaultMaxCompareBody
+	}
+
+	return func(next http.Handler) http.Handler {
+		primary, candidate := cfg.Primary(passed(next)), cfg.Candidate(passed(next))
+		primaryShadow, candidateShadow := cfg.Primary(passed(nil)), cfg.Candidate(passed(nil))
+
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				enforced, evaluated := primary, candidateShadow
+				if cfg.EnforceCandidate != nil && cfg.EnforceCandidate() {
+					enforced, evaluated = candidate, primaryShadow
+				}
+
+				if cfg.Enabled != nil && !cfg.Enabled() {
+					enforced.ServeHTTP(w, r)
+					return
+				}
+
+				r, requestID := m.withRequestID(r)
+				shadow, release, ok := m.shadowRequest(r, cfg.MaxBodyBytes)
+				defer release()
+				if !ok {
+					enforced.ServeHTTP(w, r)
+					return
+				}
+
+				var enforcedPassed bool
+				dw := &decisionWriter{ResponseWriter: w}
+				enforced.ServeHTTP(dw, r.WithContext(context.WithValue(r.Context(), compareKey, &enforcedPassed)))
+
+				var shadowPassed bool
+				sw := &shadowWriter{header: http.Header{}}
+				evaluated.ServeHTTP(sw, shadow.WithContext(context.WithValue(shadow.Context(), compareKey, &shadowPassed)))
+
+				enforcedDecision := decision(enforcedPassed, dw.status)
+				evaluatedDecision := decision(shadowPassed, sw.status)
+				if enforcedDecision == evaluatedDecision {
+					return
+				}
+
+				m.log(WarnLevel, "Middleware decisions differ", Fields{
+					"app":        m.app,
+					"request_id": requestID,
+					"middleware": cfg.Name,
+					"enforced":   enforcedDecision,
+					"evaluated":  evaluatedDecision,
+				})
+			},
+		)
+	}
+}
+
+// passed flags the request as passed on before calling next, if any
+func passed(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if flag, ok := r.Context().Value(compareKey).(*bool); ok {
+				*flag = true
+			}
+			if next != nil {
+				next.ServeHTTP(w, r)
+			}
+		},
+	)
+}
+
+// decision describes the outcome of a compared implementation
+func decision(passed bool, status int) string {
+	if passed {
+		return "pass"
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status)
+}
+
+// shadowRequest returns a copy of r for the evaluated implementation, with
+// its own body, without the request log state and marked as shadowed. It reports false when
+// the body does not fit in maxBytes or the memory budget; release must be
+// called once the request is done.
+func (m *Middleware) shadowRequest(r *http.Request, maxBytes int64) (*http.Request, func(), bool) {
+	ctx := context.WithValue(r.Context(), stateKey, (*requestState)(nil))
+	ctx = context.WithValue(ctx, shadowKey, true)
+	shadow := r.Clone(ctx)
+
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		shadow.Body = http.NoBody
+		return shadow, func() {}, true
+	}
+
+	reserve := maxBytes
+	if r.ContentLength > 0 && r.ContentLength < reserve {
+		reserve = r.ContentLength
+	}
+	if !m.budget.acquire(reserve) {
+		SetLogField(r.Context(), "buffer_skipped", true)
+		return nil, func() {}, false
+	}
+	release := func() { m.budget.release(reserve) }
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, reserve+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || int64(len(body)) > reserve {
+		return nil, release, false
+	}
+
+	shadow.Body = io.NopCloser(bytes.NewReader(body))
+	return shadow, release, true
+}
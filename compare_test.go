@@ -0,0 +1,98 @@
+package puente_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+// policy is a PolicyEngine with a fixed decision
+type policy bool
+
+func (p policy) Evaluate(ctx context.Context, input puente.PolicyInput) (bool, error) {
+	return bool(p), nil
+}
+
+func TestCompareLogsMismatch(t *testing.T) {
+	tests := []struct {
+		name             string
+		primary          policy
+		candidate        policy
+		enforceCandidate bool
+		status           int
+		enforced         string
+		evaluated        string
+	}{
+		{"agree", true, true, false, http.StatusOK, "", ""},
+		{"candidate denies", true, false, false, http.StatusOK, "pass", "403"},
+		{"primary denies", false, true, false, http.StatusForbidden, "403", "pass"},
+		{"candidate enforced", true, false, true, http.StatusForbidden, "403", "pass"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &puentetest.Logger{}
+			m := puente.NewWithLogger("app", logger)
+			events := m.Subscribe(puente.EventAccessDenied)
+			defer m.Unsubscribe(events)
+
+			h := m.Compare(puente.CompareConfig{
+				Name:             "authorize",
+				Primary:          m.Authorize(tt.primary),
+				Candidate:        m.Authorize(tt.candidate),
+				EnforceCandidate: func() bool { return tt.enforceCandidate },
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, ok := w.(http.Flusher); !ok {
+					t.Error("writer does not implement http.Flusher")
+				}
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			var differ, denied []puentetest.Entry
+			for _, e := range logger.Entries() {
+				switch e.Message {
+				case "Middleware decisions differ":
+					differ = append(differ, e)
+				case "Authorization denied":
+					denied = append(denied, e)
+				}
+			}
+
+			if tt.enforced == "" {
+				if len(differ) != 0 {
+					t.Errorf("logged %d disagreements, want none", len(differ))
+				}
+			} else {
+				if len(differ) != 1 {
+					t.Fatalf("logged %d disagreements, want 1", len(differ))
+				}
+				if got := differ[0].Fields["enforced"]; got != tt.enforced {
+					t.Errorf("enforced = %v, want %s", got, tt.enforced)
+				}
+				if got := differ[0].Fields["evaluated"]; got != tt.evaluated {
+					t.Errorf("evaluated = %v, want %s", got, tt.evaluated)
+				}
+			}
+
+			// Only the enforced implementation logs and publishes
+			wantDenied := 0
+			if tt.status == http.StatusForbidden {
+				wantDenied = 1
+			}
+			if len(denied) != wantDenied {
+				t.Errorf("logged %d denials, want %d", len(denied), wantDenied)
+			}
+			if len(events) != wantDenied {
+				t.Errorf("published %d events, want %d", len(events), wantDenied)
+			}
+		})
+	}
+}
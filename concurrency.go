@@ -0,0 +1,105 @@
+package puente
+
+import (
+	"net/http"
+	"sync"
+)
+
+// ConcurrencyLimitType is the problem type of requests rejected by
+// ConcurrencyLimit
+const ConcurrencyLimitType = "urn:puente:concurrency-limit-exceeded"
+
+// ConcurrencyConfig configures the per-identity concurrency cap
+type ConcurrencyConfig struct {
+	// Name identifies the route group in logs, as every ConcurrencyLimit
+	// middleware counts requests separately. Mount one per route group to
+	// give groups their own limit.
+	Name string
+	// Limit is the number of requests an identity may have in flight at
+	// once, long-lived ones such as WebSocket connections included. It must
+	// be positive.
+	Limit int
+	// Identity returns the identity requests are counted for, e.g. an API
+	// key ID, the authenticated user ID by default. It is logged, so it must
+	// not return secrets. Requests with an empty identity are not limited.
+	Identity func(r *http.Request) string
+}
+
+// concurrencyLimit counts the requests in flight per identity
+type concurrencyLimit struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// ConcurrencyLimit middleware rejects requests with 429 Too Many Requests
+// and a ConcurrencyLimitType problem while their identity already has
+// cfg.Limit requests in flight, so a single credential can't be shared by
+// a fleet of clients. It must be placed after the authentication
+// middleware when identifying users. It panics when cfg.Limit is not
+// positive.
+func (m *Middleware) ConcurrencyLimit(cfg ConcurrencyConfig) func(http.Handler) http.Handler {
+	if cfg.Limit <= 0 {
+		panic("puente: ConcurrencyLimit limit must be positive")
+	}
+	if cfg.Identity == nil {
+		cfg.Identity = func(r *http.Request) string { return GetUserID(r.Context()) }
+	}
+	limit := &concurrencyLimit{inFlight: map[string]int{}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				identity := cfg.Identity(r)
+				if identity == "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				if !limit.acquire(identity, cfg.Limit) {
+					m.logRequest(r.Context(), WarnLevel, "Concurrency limit exceeded", Fields{
+						"app":        m.app,
+						"request_id": GetRequestID(r.Context()),
+						"method":     r.Method,
+						"path":       r.URL.EscapedPath(),
+						"identity":   identity,
+						"group":      cfg.Name,
+						"limit":      cfg.Limit,
+					})
+					WriteProblem(w, r, Problem{
+						Type:   ConcurrencyLimitType,
+						Title:  "Concurrency limit exceeded",
+						Status: http.StatusTooManyRequests,
+					})
+					return
+				}
+				defer limit.release(identity)
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+// acquire counts a request of identity, reporting whether it is within
+// capacity
+func (c *concurrencyLimit) acquire(identity string, capacity int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight[identity] >= capacity {
+		return false
+	}
+	c.inFlight[identity]++
+	return true
+}
+
+// release uncounts a finished request of identity
+func (c *concurrencyLimit) release(identity string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight[identity]--
+	if c.inFlight[identity] <= 0 {
+		delete(c.inFlight, identity)
+	}
+}
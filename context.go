@@ -0,0 +1,26 @@
+package puente
+
+import "context"
+
+type contextKey string
+
+const (
+	// RequestIDKey is the context key holding the request ID
+	RequestIDKey contextKey = "request_id"
+	// UserIDKey is the context key holding the authenticated user ID
+	UserIDKey contextKey = "user_id"
+	// ClientIPKey is the context key holding the resolved client IP
+	ClientIPKey contextKey = "client_ip"
+)
+
+// GetRequestID returns the request ID stored in the context
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// GetUserID returns the authenticated user ID stored in the context
+func GetUserID(ctx context.Context) string {
+	id, _ := ctx.Value(UserIDKey).(string)
+	return id
+}
@@ -0,0 +1,89 @@
+package puente
+
+import "context"
+
+// loggerKey is the context key holding the middleware for GetLogger
+const loggerKey contextKey = "puente_logger"
+
+// RequestLogger logs application entries carrying the correlation fields of
+// the request it was obtained for. The zero value and nil discard entries.
+type RequestLogger struct {
+	m      *Middleware
+	fields Fields
+}
+
+// GetLogger returns a logger writing through the middleware logger with
+// the app and request_id fields of the request, trace_id and span_id when
+// traced and user_id once authenticated. Outside the Logging middleware and
+// in requests evaluated by Compare entries are discarded.
+func GetLogger(ctx context.Context) *RequestLogger {
+	m, _ := ctx.Value(loggerKey).(*Middleware)
+	if m == nil || shadowed(ctx) {
+		return &RequestLogger{}
+	}
+
+	fields := Fields{
+		"app":        m.app,
+		"request_id": GetRequestID(ctx),
+	}
+	if userID := GetUserID(ctx); userID != "" {
+		fields["user_id"] = userID
+	}
+	if t, ok := GetTraceContext(ctx); ok {
+		fields["trace_id"] = t.TraceID
+		fields["span_id"] = t.SpanID
+	}
+	return &RequestLogger{m: m, fields: fields}
+}
+
+// With returns a logger adding fields to every entry
+func (l *RequestLogger) With(fields Fields) *RequestLogger {
+	if l == nil || l.m == nil {
+		return l
+	}
+
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &RequestLogger{m: l.m, fields: merged}
+}
+
+// Log implements Logger. The correlation fields take precedence over fields.
+func (l *RequestLogger) Log(level Level, msg string, fields Fields) {
+	if l == nil || l.m == nil {
+		return
+	}
+
+	entry := make(Fields, len(l.fields)+len(fields))
+	for k, v := range fields {
+		entry[k] = v
+	}
+	for k, v := range l.fields {
+		entry[k] = v
+	}
+	l.m.log(level, msg, entry)
+}
+
+// Debug logs msg at DebugLevel
+func (l *RequestLogger) Debug(msg string, fields Fields) {
+	l.Log(DebugLevel, msg, fields)
+}
+
+// Info logs msg at InfoLevel
+func (l *RequestLogger) Info(msg string, fields Fields) {
+	l.Log(InfoLevel, msg, fields)
+}
+
+// Warn logs msg at WarnLevel
+func (l *RequestLogger) Warn(msg string, fields Fields) {
+	l.Log(WarnLevel, msg, fields)
+}
+
+// Error logs msg at ErrorLevel
+func (l *RequestLogger) Error(msg string, fields Fields) {
+	l.Log(ErrorLevel, msg, fields)
+}
@@ -0,0 +1,187 @@
+package puente
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// connKey is the context key holding the connection of a request
+const connKey contextKey = "puente_conn"
+
+// connTracker follows connections to tell why they were closed
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]*connInfo
+}
+
+// connInfo is the state of a tracked connection
+type connInfo struct {
+	since  time.Time
+	idle   bool
+	served bool
+}
+
+// trackConns makes server report the state of its connections, logging
+// connections closed for slowness. A connection closed after the header
+// timeout without ever reaching the handler is logged at Warn, evicting an
+// idle connection at Debug.
+func (s *Server) trackConns(name string, server *http.Server) {
+	headerTimeout := s.cfg.ReadHeaderTimeout
+	if headerTimeout == 0 {
+		headerTimeout = s.cfg.ReadTimeout
+	}
+
+	server.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		return context.WithValue(ctx, connKey, conn)
+	}
+	if handler := server.Handler; handler != nil {
+		server.Handler = http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if conn, ok := r.Context().Value(connKey).(net.Conn); ok {
+					s.conns.mu.Lock()
+					if info := s.conns.conns[conn]; info != nil {
+						info.served = true
+					}
+					s.conns.mu.Unlock()
+				}
+				handler.ServeHTTP(w, r)
+			},
+		)
+	}
+
+	server.ConnState = func(conn net.Conn, state http.ConnState) {
+		s.conns.mu.Lock()
+		if s.conns.conns == nil {
+			s.conns.conns = map[net.Conn]*connInfo{}
+		}
+		info := s.conns.conns[conn]
+		switch state {
+		case http.StateNew:
+			s.conns.conns[conn] = &connInfo{since: s.m.now()}
+		case http.StateIdle:
+			s.conns.conns[conn] = &connInfo{since: s.m.now(), idle: true}
+		case http.StateActive:
+			if info != nil {
+				info.idle = false
+			}
+		default:
+			delete(s.conns.conns, conn)
+		}
+		s.conns.mu.Unlock()
+
+		if state != http.StateClosed || info == nil {
+			return
+		}
+
+		open := s.m.since(info.since)
+		fields := Fields{
+			"app":         s.m.app,
+			"listener":    name,
+			"remote_addr": conn.RemoteAddr().String(),
+			"duration":    open,
+		}
+		switch {
+		case info.idle && s.cfg.IdleTimeout > 0 && open >= s.cfg.IdleTimeout:
+			s.m.log(DebugLevel, "Idle connection closed", fields)
+		case !info.idle && !info.served && headerTimeout > 0 && open >= headerTimeout:
+			fields["reason"] = "read header timeout"
+			s.m.log(WarnLevel, "Connection dropped for slowness", fields)
+		}
+	}
+}
+
+// DeadlineConfig sets per-route connection deadlines, overriding the
+// Server ReadTimeout and WriteTimeout. Zero leaves a deadline unchanged.
+type DeadlineConfig struct {
+	// ReadBody bounds reading the request body, from the start of the
+	// handler
+	ReadBody time.Duration
+	// Write bounds writing the response, from the start of the handler
+	Write time.Duration
+}
+
+// Deadlines middleware sets the read and write deadlines of the connection
+// for the wrapped routes. Requests whose body read or response write hits
+// the deadline are logged as dropped for slowness, and the access log gets
+// slow_client=true.
+func (m *Middleware) Deadlines(cfg DeadlineConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				rc := http.NewResponseController(w)
+				// Socket deadlines are wall clock times, whatever the Clock
+				now := time.Now()
+				if cfg.ReadBody > 0 {
+					rc.SetReadDeadline(now.Add(cfg.ReadBody))
+				}
+				if cfg.Write > 0 {
+					rc.SetWriteDeadline(now.Add(cfg.Write))
+				}
+
+				var once sync.Once
+				slow := func(reason string) {
+					once.Do(func() {
+						m.slowClient(r, reason)
+					})
+				}
+
+				if r.Body != nil && r.Body != http.NoBody {
+					r.Body = &deadlineBody{ReadCloser: r.Body, slow: slow}
+				}
+				next.ServeHTTP(&deadlineWriter{ResponseWriter: w, slow: slow}, r)
+			},
+		)
+	}
+}
+
+// slowClient logs a request dropped for slowness
+func (m *Middleware) slowClient(r *http.Request, reason string) {
+	SetLogField(r.Context(), "slow_client", true)
+	m.logRequest(r.Context(), WarnLevel, "Connection dropped for slowness", Fields{
+		"app":         m.app,
+		"request_id":  GetRequestID(r.Context()),
+		"remote_addr": r.RemoteAddr,
+		"method":      r.Method,
+		"path":        r.URL.EscapedPath(),
+		"reason":      reason,
+	})
+}
+
+// deadlineBody reports body reads failing on the read deadline
+type deadlineBody struct {
+	io.ReadCloser
+	slow func(reason string)
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && errors.Is(err, os.ErrDeadlineExceeded) {
+		b.slow("read body timeout")
+	}
+	return n, err
+}
+
+// deadlineWriter reports writes failing on the write deadline
+type deadlineWriter struct {
+	http.ResponseWriter
+	slow func(reason string)
+}
+
+func (d *deadlineWriter) Write(b []byte) (int, error) {
+	n, err := d.ResponseWriter.Write(b)
+	if err != nil && errors.Is(err, os.ErrDeadlineExceeded) {
+		d.slow("write timeout")
+	}
+	return n, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (d *deadlineWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
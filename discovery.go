@@ -0,0 +1,63 @@
+package puente
+
+import (
+	"net/http"
+	"strings"
+)
+
+const defaultVersionHeader = "API-Version"
+
+// DiscoveryConfig configures the discovery headers of a route group. Empty
+// fields emit no header.
+type DiscoveryConfig struct {
+	// ServiceDesc is the URL of the machine readable description, such as
+	// an OpenAPI document, linked with rel=service-desc
+	ServiceDesc string
+	// ServiceDoc is the URL of the human readable documentation, linked
+	// with rel=service-doc
+	ServiceDoc string
+	// ServiceMeta and Status are linked with rel=service-meta and
+	// rel=status (RFC 8631)
+	ServiceMeta string
+	Status      string
+	// Version is sent in VersionHeader, API-Version by default
+	Version       string
+	VersionHeader string
+}
+
+// Discovery middleware adds RFC 8631 Link headers pointing clients and SDK
+// generators to the API description and documentation, and the API version
+// header. Wrap every route group with its own configuration.
+func (m *Middleware) Discovery(cfg DiscoveryConfig) func(http.Handler) http.Handler {
+	if cfg.VersionHeader == "" {
+		cfg.VersionHeader = defaultVersionHeader
+	}
+
+	var links []string
+	for _, link := range []struct{ rel, target string }{
+		{"service-desc", cfg.ServiceDesc},
+		{"service-doc", cfg.ServiceDoc},
+		{"service-meta", cfg.ServiceMeta},
+		{"status", cfg.Status},
+	} {
+		if link.target != "" {
+			links = append(links, `<`+link.target+`>; rel="`+link.rel+`"`)
+		}
+	}
+	link := strings.Join(links, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if link != "" {
+					w.Header().Add("Link", link)
+				}
+				if cfg.Version != "" {
+					w.Header().Set(cfg.VersionHeader, cfg.Version)
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
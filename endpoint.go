@@ -0,0 +1,100 @@
+package puente
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const endpointDialTimeout = 5 * time.Second
+
+// endpoint sends framed log messages to a UDP or TCP address, redialing
+// stream connections after a failure
+type endpoint struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn
+	// frame wraps a message for stream transports
+	frame   func(b, msg []byte) []byte
+	onError func(err error)
+}
+
+// dialEndpoint connects to addr. Messages sent over a stream network are
+// framed with frame; datagram networks send one message per packet.
+func dialEndpoint(network, addr string, frame func(b, msg []byte) []byte, onError func(error)) (*endpoint, error) {
+	e := &endpoint{network: network, addr: addr, frame: frame, onError: onError}
+	if err := e.dial(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// datagram reports whether the endpoint sends packets
+func (e *endpoint) datagram() bool {
+	switch e.network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	}
+	return false
+}
+
+func (e *endpoint) dial() error {
+	conn, err := net.DialTimeout(e.network, e.addr, endpointDialTimeout)
+	if err != nil {
+		return err
+	}
+	e.conn = conn
+	return nil
+}
+
+// send writes the messages, as separate packets for datagram networks,
+// retrying once on a fresh connection for stream networks
+func (e *endpoint) send(msgs ...[]byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var err error
+	if e.datagram() {
+		for _, msg := range msgs {
+			if _, err = e.conn.Write(msg); err != nil {
+				break
+			}
+		}
+	} else {
+		var b []byte
+		for _, msg := range msgs {
+			b = e.frame(b, msg)
+		}
+		if e.conn != nil {
+			_, err = e.conn.Write(b)
+		}
+		if e.conn == nil || err != nil {
+			if e.conn != nil {
+				e.conn.Close()
+				e.conn = nil
+			}
+			if err = e.dial(); err == nil {
+				_, err = e.conn.Write(b)
+			}
+		}
+	}
+
+	if err != nil && e.onError != nil {
+		e.onError(err)
+	}
+}
+
+// fieldString formats a field value for text based formats
+func fieldString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Duration:
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return string(appendJSONValue(nil, v))
+}
@@ -0,0 +1,43 @@
+package puente
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// WithErrorStack adds the stack of the SetError call to the access log as
+// the stack field
+func WithErrorStack() Option {
+	return func(m *Middleware) {
+		m.errorStack = true
+	}
+}
+
+// SetError attaches err to the access log of the current request as the
+// error and error_type fields, error_type being the type of the innermost
+// wrapped error. The last error set wins.
+func SetError(ctx context.Context, err error) {
+	s := getState(ctx)
+	if s == nil || err == nil {
+		return
+	}
+
+	cause := err
+	for {
+		inner := errors.Unwrap(cause)
+		if inner == nil {
+			break
+		}
+		cause = inner
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fields["error"] = err
+	s.fields["error_type"] = fmt.Sprintf("%T", cause)
+	if s.stack {
+		s.fields["stack"] = string(debug.Stack())
+	}
+}
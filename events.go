@@ -0,0 +1,77 @@
+package puente
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBusFull is returned by ChannelBus when its buffer is full
+var ErrBusFull = errors.New("event bus full")
+
+// Event is a domain event derived from a request
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Time      time.Time   `json:"time"`
+	App       string      `json:"app"`
+	RequestID string      `json:"request_id,omitempty"`
+	UserID    string      `json:"user_id,omitempty"`
+	Payload   interface{} `json:"payload"`
+}
+
+// EventBus delivers events to a transport such as a channel, Kafka or SNS
+type EventBus interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// ChannelBus is an EventBus backed by a buffered channel
+type ChannelBus chan Event
+
+// Publish implements EventBus without blocking
+func (b ChannelBus) Publish(_ context.Context, event Event) error {
+	select {
+	case b <- event:
+		return nil
+	default:
+		return ErrBusFull
+	}
+}
+
+// EventEmitter publishes events enriched with the request correlation
+type EventEmitter struct {
+	m   *Middleware
+	bus EventBus
+}
+
+// NewEventEmitter returns an emitter publishing to bus
+func (m *Middleware) NewEventEmitter(bus EventBus) *EventEmitter {
+	return &EventEmitter{m: m, bus: bus}
+}
+
+// Emit publishes an event of the given type, attaching the request and user
+// IDs found in ctx
+func (e *EventEmitter) Emit(ctx context.Context, eventType string, payload interface{}) error {
+	event := Event{
+		ID:        newRequestID(),
+		Type:      eventType,
+		Time:      e.m.now(),
+		App:       e.m.app,
+		RequestID: GetRequestID(ctx),
+		UserID:    GetUserID(ctx),
+		Payload:   payload,
+	}
+
+	if err := e.bus.Publish(ctx, event); err != nil {
+		e.m.log(ErrorLevel, "Failed to publish event", Fields{
+			"app":        e.m.app,
+			"request_id": event.RequestID,
+			"event_id":   event.ID,
+			"event":      event.Type,
+			"error":      err,
+		})
+		return err
+	}
+
+	return nil
+}
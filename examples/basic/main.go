@@ -0,0 +1,50 @@
+// Command basic runs a small API behind the puente middleware: access
+// logging, basic auth, timeouts and a Server with readiness and admin
+// listeners.
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/javiertlopez/puente"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	logger := logrus.New()
+	logger.Formatter = &logrus.JSONFormatter{}
+
+	m := puente.New("basic", logger,
+		puente.WithSkipPaths("/healthz"),
+		puente.WithQueryLogging(puente.QueryLogConfig{}),
+	)
+
+	auth := m.BasicAuth(puente.NewMemoryCredentials(map[string]string{
+		"alice": "wonderland",
+	}))
+
+	api := http.NewServeMux()
+	api.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello %s\n", puente.GetUserID(r.Context()))
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/", m.Timeout(5*time.Second)(auth(api)))
+
+	admin := http.NewServeMux()
+	server := m.NewServer(puente.ServerConfig{
+		Addr:         ":8080",
+		Handler:      m.Logging(mux),
+		AdminAddr:    ":9090",
+		AdminHandler: admin,
+		DrainDelay:   5 * time.Second,
+	})
+	admin.Handle("/healthz", server.Live())
+	admin.Handle("/readyz", server.Ready())
+
+	if err := server.RunUntilSignal(); err != nil {
+		logger.WithError(err).Fatal("Server failed")
+	}
+}
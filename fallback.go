@@ -0,0 +1,215 @@
+package puente
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFallbackBuffer = 1 << 20
+	defaultRetryInterval  = time.Second
+)
+
+// FallbackPolicy decides what happens to log lines while the sink fails
+type FallbackPolicy int
+
+// Fallback policies
+const (
+	// FallbackStderr writes lines to the fallback writer, os.Stderr by
+	// default
+	FallbackStderr FallbackPolicy = iota
+	// FallbackBuffer keeps the latest lines in memory and replays them to
+	// the sink once it recovers
+	FallbackBuffer
+	// FallbackDrop discards lines, counting them
+	FallbackDrop
+)
+
+// FallbackConfig configures a FallbackWriter
+type FallbackConfig struct {
+	Policy FallbackPolicy
+	// Fallback receives lines under FallbackStderr, os.Stderr by default
+	Fallback io.Writer
+	// BufferBytes bounds the lines kept under FallbackBuffer, 1 MiB by
+	// default. The oldest lines are dropped first.
+	BufferBytes int
+	// RetryInterval spaces the attempts to write to the failing sink, one
+	// second by default. Lines in between go straight to the fallback.
+	RetryInterval time.Duration
+	// OnFailure is called when the sink starts failing
+	OnFailure func(err error)
+	// OnRecover is called when the sink works again, with the length of the
+	// outage and the lines dropped during it
+	OnRecover func(outage time.Duration, dropped int64)
+	// Clock times the retries and outages, the wall clock by default
+	Clock Clock
+}
+
+// FallbackWriter guards a log sink such as a file, socket or broker client.
+// Writes never fail: while the sink returns errors lines follow the
+// fallback policy, and the sink is retried every RetryInterval. It is safe
+// for concurrent use, and a sink that blocks instead of failing should
+// bound its own writes, e.g. with connection deadlines. Writes to the sink
+// are serialized but happen outside the state lock, so lines sent to the
+// fallback while the sink is retried never wait on it.
+type FallbackWriter struct {
+	// sinkMu serializes writes to the primary, mu guards the state
+	sinkMu  sync.Mutex
+	mu      sync.Mutex
+	primary io.Writer
+	cfg     FallbackConfig
+
+	failing   bool
+	failedAt  time.Time
+	lastRetry time.Time
+	buffered  [][]byte
+	size      int
+	dropped   int64
+	total     int64
+}
+
+// NewFallbackWriter returns a FallbackWriter for primary
+func NewFallbackWriter(primary io.Writer, cfg FallbackConfig) *FallbackWriter {
+	if cfg.Fallback == nil {
+		cfg.Fallback = os.Stderr
+	}
+	if cfg.BufferBytes == 0 {
+		cfg.BufferBytes = defaultFallbackBuffer
+	}
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = realClock{}
+	}
+
+	return &FallbackWriter{primary: primary, cfg: cfg}
+}
+
+// Write implements io.Writer, always reporting success
+func (f *FallbackWriter) Write(p []byte) (int, error) {
+	if f.retryLater(p) {
+		return len(p), nil
+	}
+
+	f.sinkMu.Lock()
+	err := f.replay()
+	if err == nil {
+		_, err = f.primary.Write(p)
+	}
+	f.sinkMu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.cfg.Clock.Now()
+	if err != nil {
+		f.fail(err, now)
+		f.fallback(p)
+		return len(p), nil
+	}
+
+	if f.failing {
+		f.failing = false
+		if f.cfg.OnRecover != nil {
+			f.cfg.OnRecover(now.Sub(f.failedAt), f.dropped)
+		}
+		f.total += f.dropped
+		f.dropped = 0
+	}
+	return len(p), nil
+}
+
+// retryLater sends p to the fallback when the sink is failing and not due
+// for a retry
+func (f *FallbackWriter) retryLater(p []byte) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failing && f.cfg.Clock.Now().Sub(f.lastRetry) < f.cfg.RetryInterval {
+		f.fallback(p)
+		return true
+	}
+	return false
+}
+
+// Dropped returns the lines dropped since the writer was created
+func (f *FallbackWriter) Dropped() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.total + f.dropped
+}
+
+// fail records a sink failure
+func (f *FallbackWriter) fail(err error, now time.Time) {
+	f.lastRetry = now
+	if f.failing {
+		return
+	}
+
+	f.failing = true
+	f.failedAt = now
+	if f.cfg.OnFailure != nil {
+		f.cfg.OnFailure(err)
+	}
+}
+
+// fallback handles a line the sink did not take
+func (f *FallbackWriter) fallback(p []byte) {
+	switch f.cfg.Policy {
+	case FallbackStderr:
+		if _, err := f.cfg.Fallback.Write(p); err != nil {
+			f.dropped++
+		}
+	case FallbackBuffer:
+		if len(p) > f.cfg.BufferBytes {
+			f.dropped++
+			return
+		}
+		for f.size+len(p) > f.cfg.BufferBytes {
+			f.size -= len(f.buffered[0])
+			f.buffered = f.buffered[1:]
+			f.dropped++
+		}
+		f.buffered = append(f.buffered, append([]byte(nil), p...))
+		f.size += len(p)
+	default:
+		f.dropped++
+	}
+}
+
+// replay writes the buffered lines to the sink with f.sinkMu held, putting
+// back those not written ahead of the lines buffered meanwhile
+func (f *FallbackWriter) replay() error {
+	f.mu.Lock()
+	pending := f.buffered
+	f.buffered, f.size = nil, 0
+	f.mu.Unlock()
+
+	for i, line := range pending {
+		if _, err := f.primary.Write(line); err != nil {
+			f.mu.Lock()
+			f.requeue(pending[i:])
+			f.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+// requeue puts lines back in front of the buffer, dropping the oldest lines
+// beyond the buffer size
+func (f *FallbackWriter) requeue(lines [][]byte) {
+	f.buffered = append(lines, f.buffered...)
+	f.size = 0
+	for _, line := range f.buffered {
+		f.size += len(line)
+	}
+	for f.size > f.cfg.BufferBytes {
+		f.size -= len(f.buffered[0])
+		f.buffered = f.buffered[1:]
+		f.dropped++
+	}
+}
@@ -0,0 +1,134 @@
+package puente_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+// sink is a log sink failing on demand
+type sink struct {
+	mu     sync.Mutex
+	down   bool
+	writes int
+	lines  []string
+}
+
+func (s *sink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes++
+	if s.down {
+		return 0, errors.New("sink down")
+	}
+	s.lines = append(s.lines, string(p))
+	return len(p), nil
+}
+
+func (s *sink) setDown(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+func TestFallbackWriterFailover(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   puente.FallbackPolicy
+		replayed string
+		fallback string
+		dropped  int64
+	}{
+		{"stderr", puente.FallbackStderr, "a\nd\n", "b\nc\n", 0},
+		{"buffer", puente.FallbackBuffer, "a\nb\nc\nd\n", "", 0},
+		{"drop", puente.FallbackDrop, "a\nd\n", "", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := puentetest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			primary := &sink{}
+			var fallback bytes.Buffer
+			var failures int
+			var outage time.Duration
+			w := puente.NewFallbackWriter(primary, puente.FallbackConfig{
+				Policy:        tt.policy,
+				Fallback:      &fallback,
+				RetryInterval: time.Second,
+				OnFailure:     func(error) { failures++ },
+				OnRecover:     func(d time.Duration, dropped int64) { outage = d },
+				Clock:         clock,
+			})
+
+			w.Write([]byte("a\n"))
+			primary.setDown(true)
+			w.Write([]byte("b\n"))
+			clock.Advance(500 * time.Millisecond)
+			w.Write([]byte("c\n"))
+			if primary.writes != 2 {
+				t.Errorf("sink written %d times, want no retry within the interval", primary.writes)
+			}
+
+			primary.setDown(false)
+			clock.Advance(time.Second)
+			w.Write([]byte("d\n"))
+
+			if got := strings.Join(primary.lines, ""); got != tt.replayed {
+				t.Errorf("sink got %q, want %q", got, tt.replayed)
+			}
+			if got := fallback.String(); got != tt.fallback {
+				t.Errorf("fallback got %q, want %q", got, tt.fallback)
+			}
+			if got := w.Dropped(); got != tt.dropped {
+				t.Errorf("dropped = %d, want %d", got, tt.dropped)
+			}
+			if failures != 1 {
+				t.Errorf("OnFailure called %d times, want 1", failures)
+			}
+			if outage != 1500*time.Millisecond {
+				t.Errorf("outage = %v, want 1.5s", outage)
+			}
+		})
+	}
+}
+
+// blockingSink blocks writes until released
+type blockingSink struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s blockingSink) Write(p []byte) (int, error) {
+	s.entered <- struct{}{}
+	<-s.release
+	return len(p), nil
+}
+
+func TestFallbackWriterDoesNotLockAcrossSinkWrites(t *testing.T) {
+	primary := blockingSink{make(chan struct{}), make(chan struct{})}
+	w := puente.NewFallbackWriter(primary, puente.FallbackConfig{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Write([]byte("line\n"))
+	}()
+	<-primary.entered
+
+	dropped := make(chan int64)
+	go func() { dropped <- w.Dropped() }()
+	select {
+	case <-dropped:
+	case <-time.After(time.Second):
+		t.Error("Dropped blocked on a sink write")
+	}
+
+	close(primary.release)
+	<-done
+}
@@ -0,0 +1,165 @@
+package puente
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FieldMapping renames or restructures the fields of every entry before it
+// is handed to the Logger
+type FieldMapping func(fields Fields) Fields
+
+// WithFieldMapping applies mapping to every entry emitted by the middleware
+func WithFieldMapping(mapping FieldMapping) Option {
+	return func(m *Middleware) {
+		m.mapping = mapping
+	}
+}
+
+// WithFieldTransition migrates entries from one field mapping to another
+// without a hard cutover: until the deadline every entry carries the fields
+// of both mappings, the new one winning on conflicting names, and only the
+// new ones afterwards. A nil from stands for the original field names.
+func WithFieldTransition(from, to FieldMapping, until time.Time) Option {
+	return func(m *Middleware) {
+		m.mapping = func(fields Fields) Fields {
+			if !m.now().Before(until) {
+				return to(fields)
+			}
+
+			old := copyFields(fields)
+			if from != nil {
+				old = from(old)
+			}
+			for k, v := range to(fields) {
+				old[k] = v
+			}
+			return old
+		}
+	}
+}
+
+// copyFields returns a shallow copy of fields, as mappings may modify them
+func copyFields(fields Fields) Fields {
+	copied := make(Fields, len(fields))
+	for k, v := range fields {
+		copied[k] = v
+	}
+	return copied
+}
+
+// RenameFields returns a FieldMapping renaming the keys found in names,
+// leaving any other field untouched
+func RenameFields(names map[string]string) FieldMapping {
+	return func(fields Fields) Fields {
+		mapped := make(Fields, len(fields))
+		for k, v := range fields {
+			if name, ok := names[k]; ok {
+				k = name
+			}
+			mapped[k] = v
+		}
+		return mapped
+	}
+}
+
+// ecsNames maps puente fields to the Elastic Common Schema
+var ecsNames = map[string]string{
+	"app":        "service.name",
+	"request_id": "http.request.id",
+	"status":     "http.response.status_code",
+	"bytes":      "http.response.body.bytes",
+	"method":     "http.request.method",
+	"path":       "url.path",
+	"query":      "url.query",
+	"duration":   "event.duration",
+	"user_id":    "user.id",
+	"client_ip":  "client.ip",
+	"user_agent": "user_agent.original",
+	"referer":    "http.request.referrer",
+	"host":       "url.domain",
+	"trace_id":   "trace.id",
+	"span_id":    "span.id",
+	"error":      "error.message",
+}
+
+// datadogNames maps puente fields to Datadog standard attributes
+var datadogNames = map[string]string{
+	"app":        "service",
+	"request_id": "http.request_id",
+	"status":     "http.status_code",
+	"bytes":      "network.bytes_written",
+	"method":     "http.method",
+	"path":       "http.url_details.path",
+	"route":      "http.route",
+	"query":      "http.url_details.queryString",
+	"duration":   "duration",
+	"user_id":    "usr.id",
+	"client_ip":  "network.client.ip",
+	"user_agent": "http.useragent",
+	"referer":    "http.referer",
+	"host":       "http.url_details.host",
+	"trace_id":   "dd.trace_id",
+	"span_id":    "dd.span_id",
+	"error":      "error.message",
+}
+
+// ECSFields maps fields to Elastic Common Schema names, with durations in
+// nanoseconds
+func ECSFields(fields Fields) Fields {
+	return nanoseconds(RenameFields(ecsNames)(fields), "event.duration")
+}
+
+// DatadogFields maps fields to Datadog standard attributes, with durations
+// in nanoseconds
+func DatadogFields(fields Fields) Fields {
+	return nanoseconds(RenameFields(datadogNames)(fields), "duration")
+}
+
+// GCPFields groups the request fields under the Google Cloud Logging
+// httpRequest object; other fields are left untouched
+func GCPFields(fields Fields) Fields {
+	httpRequest := map[string]interface{}{}
+	mapped := make(Fields, len(fields))
+
+	for k, v := range fields {
+		switch k {
+		case "method":
+			httpRequest["requestMethod"] = v
+		case "path":
+			httpRequest["requestUrl"] = v
+		case "status":
+			httpRequest["status"] = v
+		case "bytes":
+			httpRequest["responseSize"] = fmt.Sprint(v)
+		case "duration":
+			if d, ok := v.(time.Duration); ok {
+				httpRequest["latency"] = strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+			}
+		case "user_agent":
+			httpRequest["userAgent"] = v
+		case "referer":
+			httpRequest["referer"] = v
+		case "client_ip":
+			httpRequest["remoteIp"] = v
+		case "proto":
+			httpRequest["protocol"] = v
+		default:
+			mapped[k] = v
+		}
+	}
+
+	if len(httpRequest) > 0 {
+		mapped["httpRequest"] = httpRequest
+	}
+	return mapped
+}
+
+// nanoseconds converts the duration field key to integer nanoseconds
+func nanoseconds(fields Fields, key string) Fields {
+	if d, ok := fields[key].(time.Duration); ok {
+		fields[key] = d.Nanoseconds()
+	}
+	return fields
+}
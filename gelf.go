@@ -0,0 +1,138 @@
+package puente
+
+import (
+	"crypto/rand"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// gelfChunkSize keeps chunked GELF packets within common MTUs
+	gelfChunkSize = 1420
+	gelfMaxChunks = 128
+)
+
+// GELFConfig configures a GELF Logger
+type GELFConfig struct {
+	// Host is the host field, the host name by default
+	Host string
+	// OnError is called when a message cannot be sent
+	OnError func(err error)
+	// Clock timestamps the messages, the wall clock by default
+	Clock Clock
+}
+
+// gelfLogger writes entries as GELF 1.1 messages
+type gelfLogger struct {
+	host     string
+	clock    Clock
+	endpoint *endpoint
+}
+
+// DialGELF returns a Logger sending entries as GELF messages to a Graylog
+// UDP or TCP input. UDP messages larger than a packet are chunked; TCP
+// messages are null delimited. Fields become additional fields, and access
+// log entries get "METHOD path status" as short_message.
+func DialGELF(network, addr string, cfg GELFConfig) (Logger, error) {
+	if cfg.Host == "" {
+		cfg.Host, _ = os.Hostname()
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = realClock{}
+	}
+
+	frame := func(b, msg []byte) []byte {
+		return append(append(b, msg...), 0)
+	}
+	e, err := dialEndpoint(network, addr, frame, cfg.OnError)
+	if err != nil {
+		return nil, err
+	}
+	return &gelfLogger{host: cfg.Host, clock: cfg.Clock, endpoint: e}, nil
+}
+
+// Log implements Logger
+func (l *gelfLogger) Log(level Level, msg string, fields Fields) {
+	b := append([]byte(nil), `{"version":"1.1","host":`...)
+	b = appendJSONString(b, l.host)
+	b = append(b, `,"short_message":`...)
+	b = appendJSONString(b, shortMessage(msg, fields))
+	b = append(b, `,"timestamp":`...)
+	now := l.clock.Now()
+	b = strconv.AppendFloat(b, float64(now.UnixMilli())/1000, 'f', 3, 64)
+	b = append(b, `,"level":`...)
+	b = strconv.AppendInt(b, int64(syslogSeverity(level)), 10)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "id" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b = append(b, ',')
+		b = appendJSONString(b, "_"+k)
+		b = append(b, ':')
+		switch v := fields[k].(type) {
+		case int, int32, int64, uint, uint64, time.Duration:
+			b = appendJSONValue(b, v)
+		default:
+			b = appendJSONString(b, fieldString(v))
+		}
+	}
+	b = append(b, '}')
+
+	if !l.endpoint.datagram() || len(b) <= gelfChunkSize {
+		l.endpoint.send(b)
+		return
+	}
+	if chunks := gelfChunks(b); chunks != nil {
+		l.endpoint.send(chunks...)
+	}
+}
+
+// gelfChunks splits a message into GELF chunks, or returns nil when it
+// needs more than the 128 chunks allowed
+func gelfChunks(b []byte) [][]byte {
+	const header = 12
+	size := gelfChunkSize - header
+	count := (len(b) + size - 1) / size
+	if count > gelfMaxChunks {
+		return nil
+	}
+
+	var id [8]byte
+	rand.Read(id[:])
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(b) {
+			end = len(b)
+		}
+
+		chunk := make([]byte, 0, header+end-i*size)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, b[i*size:end]...))
+	}
+	return chunks
+}
+
+// shortMessage returns msg, or a summary of an access log entry
+func shortMessage(msg string, fields Fields) string {
+	if msg != "" {
+		return msg
+	}
+
+	method, _ := fields["method"].(string)
+	path, _ := fields["path"].(string)
+	if method == "" && path == "" {
+		return "-"
+	}
+	return method + " " + path + " " + fieldString(fields["status"])
+}
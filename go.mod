@@ -1,6 +1,6 @@
 module github.com/javiertlopez/puente
 
-go 1.17
+go 1.23
 
 require github.com/sirupsen/logrus v1.8.1
 
@@ -0,0 +1,102 @@
+package puente
+
+import (
+	"net/http"
+	"regexp"
+)
+
+// redacted replaces the value of redacted headers
+const redacted = "[REDACTED]"
+
+// defaultRedactedHeaders are always redacted
+var defaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// HeaderLogConfig configures which headers are added to the access log
+type HeaderLogConfig struct {
+	// Request and Response list the headers to log
+	Request  []string
+	Response []string
+	// Redact lists additional headers whose values are replaced, on top of
+	// Authorization, Cookie, Set-Cookie and Proxy-Authorization
+	Redact []string
+	// RedactPatterns redacts every header whose name matches
+	RedactPatterns []*regexp.Regexp
+}
+
+// headerLog is the compiled header logging configuration
+type headerLog struct {
+	request  []string
+	response []string
+	redact   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// WithHeaderLogging adds the configured request and response headers to
+// the access log as request_headers and response_headers
+func WithHeaderLogging(cfg HeaderLogConfig) Option {
+	h := &headerLog{
+		request:  canonicalHeaders(cfg.Request),
+		response: canonicalHeaders(cfg.Response),
+		redact:   map[string]bool{},
+		patterns: cfg.RedactPatterns,
+	}
+	for _, name := range canonicalHeaders(append(defaultRedactedHeaders, cfg.Redact...)) {
+		h.redact[name] = true
+	}
+
+	return func(m *Middleware) {
+		m.headerLog = h
+	}
+}
+
+// canonicalHeaders returns the canonical form of header names
+func canonicalHeaders(names []string) []string {
+	canonical := make([]string, len(names))
+	for i, name := range names {
+		canonical[i] = http.CanonicalHeaderKey(name)
+	}
+	return canonical
+}
+
+// addHeaders adds the configured headers of the exchange to fields
+func (h *headerLog) addHeaders(fields Fields, r *http.Request, w http.ResponseWriter) {
+	if h == nil {
+		return
+	}
+
+	if headers := h.collect(r.Header, h.request); len(headers) > 0 {
+		fields["request_headers"] = headers
+	}
+	if headers := h.collect(w.Header(), h.response); len(headers) > 0 {
+		fields["response_headers"] = headers
+	}
+}
+
+// collect picks the named headers, redacting sensitive ones
+func (h *headerLog) collect(header http.Header, names []string) map[string]string {
+	headers := map[string]string{}
+	for _, name := range names {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		if h.redacted(name) {
+			value = redacted
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// redacted reports whether the value of the named header must be hidden
+func (h *headerLog) redacted(name string) bool {
+	if h.redact[name] {
+		return true
+	}
+	for _, pattern := range h.patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
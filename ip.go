@@ -0,0 +1,40 @@
+package puente
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// DefaultIPv6GroupBits groups IPv6 clients by their /64 network, the
+// smallest block normally assigned to a single subscriber
+const DefaultIPv6GroupBits = 64
+
+// CanonicalIP parses s as an IP address in canonical form: surrounding
+// brackets and the IPv6 zone are stripped and IPv4-mapped IPv6 addresses
+// are unmapped, so the same client always yields the same address
+func CanonicalIP(s string) (netip.Addr, error) {
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "["), "]")
+
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return ip.WithZone("").Unmap(), nil
+}
+
+// IPGroup returns the network used to key per-client state such as rate
+// limits: a single address for IPv4, and for IPv6 its /bits network
+// (DefaultIPv6GroupBits when bits is 0) so a client rotating addresses
+// through privacy extensions is still counted once
+func IPGroup(ip netip.Addr, bits int) netip.Prefix {
+	ip = ip.WithZone("").Unmap()
+	if ip.Is4() {
+		return netip.PrefixFrom(ip, 32)
+	}
+
+	if bits <= 0 || bits > 128 {
+		bits = DefaultIPv6GroupBits
+	}
+	prefix, _ := ip.Prefix(bits)
+	return prefix
+}
@@ -0,0 +1,188 @@
+package puente
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned by a JobStore for unknown job IDs
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStatus is the lifecycle state of an asynchronous job
+type JobStatus string
+
+// Job lifecycle states
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is the status resource of an asynchronous job
+type Job struct {
+	ID        string          `json:"id"`
+	RequestID string          `json:"request_id"`
+	Status    JobStatus       `json:"status"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// JobStore persists jobs
+type JobStore interface {
+	Save(ctx context.Context, job Job) error
+	Get(ctx context.Context, id string) (Job, error)
+}
+
+// JobFunc is the long-running work of a job; its result is JSON encoded
+type JobFunc func(ctx context.Context) (interface{}, error)
+
+// MemoryJobStore is an in-memory JobStore
+type MemoryJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+// NewMemoryJobStore returns an empty in-memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: map[string]Job{}}
+}
+
+// Save implements JobStore
+func (s *MemoryJobStore) Save(_ context.Context, job Job) error {
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+	return nil
+}
+
+// Get implements JobStore
+func (s *MemoryJobStore) Get(_ context.Context, id string) (Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Jobs converts long-running handler work into 202 Accepted responses with
+// a status resource served under statusPath
+type Jobs struct {
+	m          *Middleware
+	store      JobStore
+	statusPath string
+}
+
+// NewJobs returns a job runner backed by store. statusPath is the prefix
+// the Status handler is mounted on, e.g. "/jobs/".
+func (m *Middleware) NewJobs(store JobStore, statusPath string) *Jobs {
+	return &Jobs{
+		m:          m,
+		store:      store,
+		statusPath: statusPath,
+	}
+}
+
+// Submit runs fn in the background and replies 202 Accepted with a Location
+// header pointing at the job status resource
+func (j *Jobs) Submit(w http.ResponseWriter, r *http.Request, fn JobFunc) {
+	r, requestID := j.m.withRequestID(r)
+
+	now := j.m.now()
+	job := Job{
+		ID:        newRequestID(),
+		RequestID: requestID,
+		Status:    JobPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// The job outlives the request, so only its correlation is carried over
+	ctx := context.WithValue(context.Background(), RequestIDKey, requestID)
+	if !j.save(ctx, job) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	go j.run(ctx, job, fn)
+
+	w.Header().Set("Location", j.statusPath+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// Status returns a handler serving job status resources
+func (j *Jobs) Status() http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimPrefix(r.URL.Path, j.statusPath)
+
+			job, err := j.store.Get(r.Context(), id)
+			if errors.Is(err, ErrJobNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(job)
+		},
+	)
+}
+
+// run executes fn and records the job lifecycle
+func (j *Jobs) run(ctx context.Context, job Job, fn JobFunc) {
+	job.Status = JobRunning
+	job.UpdatedAt = j.m.now()
+	j.save(ctx, job)
+
+	result, err := fn(ctx)
+	if err == nil {
+		job.Result, err = json.Marshal(result)
+	}
+
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = JobSucceeded
+	}
+	job.UpdatedAt = j.m.now()
+	j.save(ctx, job)
+}
+
+// save stores the job and logs the lifecycle transition
+func (j *Jobs) save(ctx context.Context, job Job) bool {
+	fields := Fields{
+		"app":        j.m.app,
+		"request_id": job.RequestID,
+		"job_id":     job.ID,
+		"job_status": job.Status,
+	}
+
+	if err := j.store.Save(ctx, job); err != nil {
+		fields["error"] = err
+		j.m.log(ErrorLevel, "Failed to save job", fields)
+		return false
+	}
+
+	if job.Error != "" {
+		fields["error"] = job.Error
+	}
+	j.m.log(InfoLevel, "Job updated", fields)
+	return true
+}
@@ -0,0 +1,182 @@
+package puente
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// jsonLogger is a Logger encoding entries as JSON lines without reflection
+// for the common field types. Output matches the logrus JSON formatter:
+// keys sorted, level/msg/time keys and RFC 3339 timestamps.
+type jsonLogger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	clock Clock
+}
+
+// jsonBuffer is a pooled encoding buffer
+type jsonBuffer struct {
+	b    []byte
+	keys []string
+}
+
+var jsonBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &jsonBuffer{b: make([]byte, 0, 1024), keys: make([]string, 0, 16)}
+	},
+}
+
+// NewJSONLogger returns a high throughput Logger writing JSON lines to out
+func NewJSONLogger(out io.Writer) Logger {
+	return NewJSONLoggerWithClock(out, realClock{})
+}
+
+// NewJSONLoggerWithClock returns a JSON Logger timestamping entries with
+// clock, for reproducible output in tests
+func NewJSONLoggerWithClock(out io.Writer, clock Clock) Logger {
+	return &jsonLogger{out: out, clock: clock}
+}
+
+// Log implements Logger
+func (l *jsonLogger) Log(level Level, msg string, fields Fields) {
+	buf := jsonBufferPool.Get().(*jsonBuffer)
+	defer jsonBufferPool.Put(buf)
+
+	buf.keys = buf.keys[:0]
+	for k := range fields {
+		switch k {
+		case "level", "msg", "time":
+			// Reserved keys, as logrus they are prefixed with fields.
+			buf.keys = append(buf.keys, "fields."+k)
+		default:
+			buf.keys = append(buf.keys, k)
+		}
+	}
+	buf.keys = append(buf.keys, "level", "msg", "time")
+	sort.Strings(buf.keys)
+
+	b := append(buf.b[:0], '{')
+	for i, k := range buf.keys {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, k)
+		b = append(b, ':')
+
+		switch k {
+		case "level":
+			b = appendJSONString(b, level.String())
+		case "msg":
+			b = appendJSONString(b, msg)
+		case "time":
+			b = append(b, '"')
+			b = l.clock.Now().AppendFormat(b, time.RFC3339)
+			b = append(b, '"')
+		case "fields.level", "fields.msg", "fields.time":
+			b = appendJSONValue(b, fields[k[len("fields."):]])
+		default:
+			b = appendJSONValue(b, fields[k])
+		}
+	}
+	b = append(b, '}', '\n')
+	buf.b = b
+
+	l.mu.Lock()
+	l.out.Write(b)
+	l.mu.Unlock()
+}
+
+// appendJSONValue encodes the common field types directly, falling back to
+// encoding/json for anything else
+func appendJSONValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...)
+	case string:
+		return appendJSONString(b, v)
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case time.Duration:
+		return strconv.AppendInt(b, int64(v), 10)
+	case error:
+		return appendJSONString(b, v.Error())
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(b, err.Error())
+	}
+	return append(b, encoded...)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString quotes s the way encoding/json does with HTML escaping
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,52 @@
+package puente_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJSONLoggerMatchesLogrus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	var controls []byte
+	for c := byte(0); c < 0x20; c++ {
+		controls = append(controls, c)
+	}
+	values := []string{
+		"plain",
+		string(controls),
+		"quote \" backslash \\ slash /",
+		"<script>&amp;</script>",
+		"line\u2028separator\u2029",
+		"invalid \xff utf-8",
+		"back\bspace form\ffeed del\x7f",
+	}
+
+	for _, v := range values {
+		fields := puente.Fields{"value": v, "status": 200, "msg": v}
+
+		var got bytes.Buffer
+		puente.NewJSONLoggerWithClock(&got, puentetest.NewClock(now)).Log(puente.WarnLevel, v, fields)
+
+		entry := &logrus.Entry{
+			Logger:  logrus.New(),
+			Data:    logrus.Fields(fields),
+			Time:    now,
+			Level:   logrus.WarnLevel,
+			Message: v,
+		}
+		want, err := (&logrus.JSONFormatter{}).Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.String() != string(want) {
+			t.Errorf("value %q:\n got %s\nwant %s", v, got.String(), want)
+		}
+	}
+}
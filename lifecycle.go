@@ -0,0 +1,161 @@
+package puente
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultWatchInterval = 10 * time.Second
+
+// Check reports the health of a dependency such as an upstream service
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// lifecycle tracks the readiness of a Server
+type lifecycle struct {
+	mu        sync.RWMutex
+	ready     bool
+	reloadErr error
+}
+
+func (l *lifecycle) setReady(ready bool) {
+	l.mu.Lock()
+	l.ready = ready
+	l.mu.Unlock()
+}
+
+func (l *lifecycle) setReloadErr(err error) {
+	l.mu.Lock()
+	l.reloadErr = err
+	l.mu.Unlock()
+}
+
+func (l *lifecycle) status() (bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.ready, l.reloadErr
+}
+
+// RunUntilSignal runs the server until SIGTERM or SIGINT is received
+func (s *Server) RunUntilSignal() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	return s.Run(ctx)
+}
+
+// Live returns a liveness handler, always answering 200 while the process
+// serves requests
+func (s *Server) Live() http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		},
+	)
+}
+
+// Ready returns a readiness handler. It answers 503 before the listeners
+// start, while draining, after a failed config reload and whenever a
+// readiness check fails, reporting every check in the body.
+func (s *Server) Ready() http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			ready, reloadErr := s.lifecycle.status()
+
+			checks := map[string]string{}
+			if reloadErr != nil {
+				checks["config"] = reloadErr.Error()
+				ready = false
+			}
+			for _, c := range s.cfg.ReadinessChecks {
+				if err := c.Check(r.Context()); err != nil {
+					checks[c.Name] = err.Error()
+					ready = false
+				} else {
+					checks[c.Name] = "ok"
+				}
+			}
+
+			status := http.StatusOK
+			if !ready {
+				status = http.StatusServiceUnavailable
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"ready":  ready,
+				"checks": checks,
+			})
+		},
+	)
+}
+
+// drain marks the server as not ready and waits for the drain delay, so
+// load balancers stop routing to it before the listeners close
+func (s *Server) drain() {
+	s.lifecycle.setReady(false)
+	if s.cfg.DrainDelay <= 0 {
+		return
+	}
+
+	s.m.log(InfoLevel, "Draining", Fields{
+		"app":         s.m.app,
+		"drain_delay": s.cfg.DrainDelay,
+	})
+	sleep(s.m.clock, s.cfg.DrainDelay)
+}
+
+// watch polls the watched file and calls the reload hook when its
+// modification time changes, e.g. when a mounted ConfigMap is updated
+func (s *Server) watch(ctx context.Context) {
+	if s.cfg.WatchFile == "" || s.cfg.OnReload == nil {
+		return
+	}
+
+	interval := s.cfg.WatchInterval
+	if interval == 0 {
+		interval = defaultWatchInterval
+	}
+
+	var modTime time.Time
+	if info, err := os.Stat(s.cfg.WatchFile); err == nil {
+		modTime = info.ModTime()
+	}
+
+	timer := s.m.clock.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C():
+			timer.Reset(interval)
+		case <-ctx.Done():
+			return
+		}
+
+		info, err := os.Stat(s.cfg.WatchFile)
+		if err != nil || info.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+
+		fields := Fields{"app": s.m.app, "file": s.cfg.WatchFile}
+		err = s.cfg.OnReload()
+		s.lifecycle.setReloadErr(err)
+		if err != nil {
+			fields["error"] = err
+			s.m.log(ErrorLevel, "Config reload failed", fields)
+			continue
+		}
+		s.m.log(InfoLevel, "Config reloaded", fields)
+	}
+}
@@ -0,0 +1,86 @@
+package puente
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// String returns the lower case level name
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warning"
+	case ErrorLevel:
+		return "error"
+	}
+	return "unknown"
+}
+
+// ParseLevel parses a level name as returned by String, also accepting
+// "warn"
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// Fields are the structured fields of a log entry
+type Fields map[string]interface{}
+
+// Logger is the structured logger the middleware writes to. Adapters are
+// provided for logrus; any other library can be plugged in by implementing
+// this single method.
+type Logger interface {
+	Log(level Level, msg string, fields Fields)
+}
+
+// log emits an entry through the configured logger
+func (m *Middleware) log(level Level, msg string, fields Fields) {
+	if level < m.control.level() {
+		return
+	}
+	m.emit(level, msg, fields)
+}
+
+// logRequest logs an entry about the request of ctx, dropping it when the
+// request is a shadow copy evaluated by Compare
+func (m *Middleware) logRequest(ctx context.Context, level Level, msg string, fields Fields) {
+	if shadowed(ctx) {
+		return
+	}
+	m.log(level, msg, fields)
+}
+
+// emit writes an entry regardless of the configured minimum level
+func (m *Middleware) emit(level Level, msg string, fields Fields) {
+	msg, fields = m.scrub(msg, fields)
+	m.guard(fields)
+	if m.mapping != nil {
+		fields = m.mapping(fields)
+	}
+	m.logger.Log(level, msg, fields)
+}
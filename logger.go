@@ -1,20 +1,26 @@
 package puente
 
 import (
+	"bufio"
+	"context"
+	"net"
 	"net/http"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
+
+	// capture, when set, keeps the start of textual response bodies
+	capture *captureBuffer
+	textual func(contentType string) bool
 }
 
 // ResponseWriter returns a responseWritter wrapper to access the http status
 func newResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{w, http.StatusOK}
+	return &responseWriter{w, http.StatusOK, 0, nil, nil}
 }
 
 // WriteHeader keeps the status code
@@ -23,22 +29,201 @@ func (r *responseWriter) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap returns the wrapped writer for http.ResponseController
+func (r *responseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush implements http.Flusher for streaming handlers asserting it
+func (r *responseWriter) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker for handlers taking over the connection
+func (r *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+// Write counts the response body bytes
+func (r *responseWriter) Write(b []byte) (int, error) {
+	if r.capture != nil && r.bytes == 0 {
+		contentType := r.Header().Get("Content-Type")
+		if contentType == "" {
+			contentType = http.DetectContentType(b)
+		}
+		if !r.textual(contentType) {
+			r.capture = nil
+		}
+	}
+
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	if r.capture != nil {
+		r.capture.Write(b[:n])
+	}
+	return n, err
+}
+
+// StatusLevel is the default access log level mapping: 5xx responses are
+// logged at Error, 4xx at Warn and everything else at Info
+func StatusLevel(status int) Level {
+	switch {
+	case status >= 500:
+		return ErrorLevel
+	case status >= 400:
+		return WarnLevel
+	}
+	return InfoLevel
+}
+
+// WithLevelFunc sets the mapping from response status to access log level
+func WithLevelFunc(level func(status int) Level) Option {
+	return func(m *Middleware) {
+		m.level = level
+	}
+}
+
+// WithSlowRequestThreshold logs requests taking longer than threshold at
+// Warn, or higher if their status calls for it, with a slow=true field
+func WithSlowRequestThreshold(threshold time.Duration) Option {
+	return func(m *Middleware) {
+		m.slow = threshold
+	}
+}
+
+// WithRequestStartLog logs a "Request received" entry as each request
+// starts, so in-flight requests are visible, and a "Request completed"
+// access log entry once it is done. Both entries follow the same sampling
+// decision; a request sampled out still logs its completion at Warn and
+// above.
+func WithRequestStartLog() Option {
+	return func(m *Middleware) {
+		m.startLog = true
+	}
+}
+
+// ResponseInfo describes the response of a completed request
+type ResponseInfo struct {
+	Status   int
+	Bytes    int64
+	Duration time.Duration
+	Header   http.Header
+}
+
+// WithLogFields appends the fields returned by fn to every access log entry.
+// Fields set by the Logging middleware itself take precedence.
+func WithLogFields(fn func(r *http.Request, w ResponseInfo) Fields) Option {
+	return func(m *Middleware) {
+		m.logFields = fn
+	}
+}
+
 // Logging middleware logs the request
 func (m *Middleware) Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
+			start := m.now()
+			if m.skip.match(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
 
+			r, requestID := m.withRequestID(r)
+			if m.responseIDHeader != "" {
+				w.Header().Set(m.responseIDHeader, requestID)
+			}
+			var trace TraceContext
+			if m.tracing != 0 {
+				r, trace = m.withTrace(r)
+			}
+			clientIP := m.clientIP(r)
+			ctx := context.WithValue(r.Context(), ClientIPKey, clientIP)
+			ctx, state := newState(context.WithValue(ctx, loggerKey, m))
+			state.stack = m.errorStack
+			// Decided once so start and completion entries come in pairs
+			sampled := m.control.sample()
+			if m.startLog && sampled {
+				m.log(InfoLevel, "Request received", Fields{
+					"app":        m.app,
+					"request_id": requestID,
+					"client_ip":  clientIP,
+					"method":     r.Method,
+					"path":       r.URL.EscapedPath(),
+				})
+			}
 			wrapped := newResponseWriter(w)
-			next.ServeHTTP(wrapped, r)
-
-			m.logger.WithFields(log.Fields{
-				"app":      m.app,
-				"status":   wrapped.statusCode,
-				"method":   r.Method,
-				"path":     r.URL.EscapedPath(),
-				"duration": time.Since(start),
-			}).Info()
+			addBodies, releaseBodies := m.captureBodies(r, wrapped)
+			defer releaseBodies()
+			inner := r.WithContext(ctx)
+			next.ServeHTTP(wrapped, inner)
+			recordContextError(ctx)
+			duration := m.since(start)
+
+			fields := state.snapshot()
+			addBodies(fields)
+			if m.commonLog != nil {
+				userID, _ := fields["user_id"].(string)
+				m.writeCommonLog(r, clientIP, userID, wrapped.statusCode, wrapped.bytes, start)
+				if m.commonLog.cfg.Only {
+					return
+				}
+			}
+
+			m.headerLog.addHeaders(fields, r, wrapped)
+			m.queryLog.addQuery(fields, r.URL.RawQuery)
+			m.addRequestFields(fields, r)
+			route := m.route(fields, inner)
+			if m.logFields != nil {
+				info := ResponseInfo{
+					Status:   wrapped.statusCode,
+					Bytes:    wrapped.bytes,
+					Duration: duration,
+					Header:   wrapped.Header(),
+				}
+				for k, v := range m.logFields(r, info) {
+					fields[k] = v
+				}
+			}
+			if parked, ok := fields["parked_duration"].(time.Duration); ok {
+				fields["processing_duration"] = duration - parked
+			}
+			if handler, ok := fields["handler_duration"].(time.Duration); ok {
+				fields["middleware_duration"] = duration - handler
+			}
+
+			fields["app"] = m.app
+			fields["request_id"] = requestID
+			fields["client_ip"] = clientIP
+			fields["status"] = wrapped.statusCode
+			fields["bytes"] = wrapped.bytes
+			fields["method"] = r.Method
+			fields["path"] = r.URL.EscapedPath()
+			fields["duration"] = duration
+			if trace.TraceID != "" {
+				fields["trace_id"] = trace.TraceID
+				fields["span_id"] = trace.SpanID
+			}
+			if route != "" && m.routes.ReplacePath {
+				fields["path"] = route
+			}
+
+			level := m.level(wrapped.statusCode)
+			if m.slow > 0 && duration > m.slow {
+				fields["slow"] = true
+				if level < WarnLevel {
+					level = WarnLevel
+				}
+			}
+
+			if level < WarnLevel && !sampled {
+				return
+			}
+
+			msg := ""
+			if m.startLog {
+				msg = "Request completed"
+			}
+			m.log(level, msg, fields)
 		},
 	)
 }
@@ -0,0 +1,153 @@
+package puente
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// logControl holds the log level and sampling rate changeable at runtime
+type logControl struct {
+	minLevel   atomic.Int32
+	sampleRate atomic.Uint64
+}
+
+func newLogControl() *logControl {
+	c := &logControl{}
+	c.minLevel.Store(int32(DebugLevel))
+	c.sampleRate.Store(math.Float64bits(1))
+	return c
+}
+
+func (c *logControl) level() Level {
+	return Level(c.minLevel.Load())
+}
+
+func (c *logControl) rate() float64 {
+	return math.Float64frombits(c.sampleRate.Load())
+}
+
+// sample reports whether an access log entry is kept
+func (c *logControl) sample() bool {
+	rate := c.rate()
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// WithLogLevel sets the initial minimum level of emitted entries, Debug by
+// default
+func WithLogLevel(level Level) Option {
+	return func(m *Middleware) {
+		m.SetLogLevel(level)
+	}
+}
+
+// WithSampleRate sets the initial fraction of access log entries below
+// Warn that are emitted, 1 by default
+func WithSampleRate(rate float64) Option {
+	return func(m *Middleware) {
+		m.SetSampleRate(rate)
+	}
+}
+
+// SetLogLevel changes the minimum level of emitted entries at runtime
+func (m *Middleware) SetLogLevel(level Level) {
+	m.control.minLevel.Store(int32(level))
+}
+
+// LogLevel returns the minimum level of emitted entries
+func (m *Middleware) LogLevel() Level {
+	return m.control.level()
+}
+
+// SetSampleRate changes at runtime the fraction, between 0 and 1, of access
+// log entries below Warn that are emitted. Warnings and errors are never
+// sampled out.
+func (m *Middleware) SetSampleRate(rate float64) {
+	if rate < 0 || math.IsNaN(rate) {
+		rate = 0
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	m.control.sampleRate.Store(math.Float64bits(rate))
+}
+
+// SampleRate returns the fraction of access log entries below Warn emitted
+func (m *Middleware) SampleRate() float64 {
+	return m.control.rate()
+}
+
+// logSettings is the body of the log level handler
+type logSettings struct {
+	Level      string   `json:"level"`
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+}
+
+// LogLevelHandler returns an admin handler reporting the log level and
+// sample rate on GET and changing them on PUT with a JSON body such as
+// {"level":"debug","sample_rate":0.5}. Requests must carry the token as
+// "Authorization: Bearer <token>"; with an empty token every request is
+// rejected.
+func (m *Middleware) LogLevelHandler(token string) http.Handler {
+	expected := sha256.Sum256([]byte(token))
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			digest := sha256.Sum256([]byte(given))
+			if token == "" || !ok || subtle.ConstantTimeCompare(expected[:], digest[:]) != 1 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+			case http.MethodPut:
+				var settings logSettings
+				if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				level := m.LogLevel()
+				if settings.Level != "" {
+					var err error
+					if level, err = ParseLevel(settings.Level); err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+				}
+				rate := m.SampleRate()
+				if settings.SampleRate != nil {
+					rate = *settings.SampleRate
+				}
+
+				m.SetLogLevel(level)
+				m.SetSampleRate(rate)
+				// Always recorded, whatever the new level
+				m.emit(InfoLevel, "Log settings changed", Fields{
+					"app":         m.app,
+					"request_id":  GetRequestID(r.Context()),
+					"log_level":   level.String(),
+					"sample_rate": m.SampleRate(),
+				})
+			default:
+				w.Header().Set("Allow", "GET, PUT")
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+
+			rate := m.SampleRate()
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(logSettings{
+				Level:      m.LogLevel().String(),
+				SampleRate: &rate,
+			})
+		},
+	)
+}
@@ -0,0 +1,67 @@
+package puente_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestSamplingPairsStartAndCompletion(t *testing.T) {
+	tests := []struct {
+		name   string
+		rate   float64
+		status int
+	}{
+		{"kept", 1, http.StatusOK},
+		{"dropped", 0, http.StatusOK},
+		{"half", 0.5, http.StatusOK},
+		{"errors never sampled out", 0, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &puentetest.Logger{}
+			m := puente.NewWithLogger("app", logger, puente.WithRequestStartLog(), puente.WithSampleRate(tt.rate))
+			h := m.Logging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+
+			const requests = 200
+			for i := 0; i < requests; i++ {
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+
+			received := map[string]bool{}
+			completed := 0
+			for _, e := range logger.Entries() {
+				switch e.Message {
+				case "Request received":
+					received[e.Fields["request_id"].(string)] = true
+				case "Request completed":
+					completed++
+					id := e.Fields["request_id"].(string)
+					if !received[id] && tt.status < http.StatusBadRequest {
+						t.Errorf("request %s completed without a start entry", id)
+					}
+				}
+			}
+
+			switch {
+			case tt.status >= http.StatusInternalServerError:
+				if completed != requests {
+					t.Errorf("completed %d, want every request", completed)
+				}
+			case tt.rate == 1 || tt.rate == 0:
+				if want := int(tt.rate * requests); completed != want || len(received) != want {
+					t.Errorf("received %d, completed %d, want %d each", len(received), completed, want)
+				}
+			default:
+				if completed != len(received) {
+					t.Errorf("received %d, completed %d, want pairs", len(received), completed)
+				}
+			}
+		})
+	}
+}
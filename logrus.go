@@ -0,0 +1,29 @@
+package puente
+
+import "github.com/sirupsen/logrus"
+
+// logrusLogger adapts a logrus logger to Logger
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+// NewLogrusLogger returns a Logger writing to a logrus logger or entry
+func NewLogrusLogger(logger logrus.FieldLogger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+// Log implements Logger
+func (l logrusLogger) Log(level Level, msg string, fields Fields) {
+	entry := l.logger.WithFields(logrus.Fields(fields))
+
+	switch level {
+	case DebugLevel:
+		entry.Debug(msg)
+	case WarnLevel:
+		entry.Warn(msg)
+	case ErrorLevel:
+		entry.Error(msg)
+	default:
+		entry.Info(msg)
+	}
+}
@@ -0,0 +1,67 @@
+package puente
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultPollInterval is the ParkFunc polling interval used when the
+// given one is not positive
+const defaultPollInterval = 100 * time.Millisecond
+
+// ErrPollTimeout is returned by Park when the maximum wait elapses
+var ErrPollTimeout = errors.New("long poll timed out")
+
+// Park blocks a long-poll request until ready is signalled, maxWait elapses
+// or the client goes away. The time spent parked is added to the access log
+// as parked_duration so it can be told apart from processing time.
+func Park(ctx context.Context, ready <-chan struct{}, maxWait time.Duration) error {
+	clock := clockFrom(ctx)
+	start := clock.Now()
+	defer func() {
+		addLogDuration(ctx, "parked_duration", clock.Now().Sub(start))
+	}()
+
+	timer := clock.NewTimer(maxWait)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+		return nil
+	case <-timer.C():
+		return ErrPollTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ParkFunc is like Park but waits on a condition polled every interval,
+// 100ms when interval is not positive
+func ParkFunc(ctx context.Context, cond func() bool, interval, maxWait time.Duration) error {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	ready := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+
+	clock := clockFrom(ctx)
+	go func() {
+		timer := clock.NewTimer(interval)
+		defer timer.Stop()
+
+		for !cond() {
+			select {
+			case <-timer.C():
+				timer.Reset(interval)
+			case <-stop:
+				return
+			}
+		}
+		close(ready)
+	}()
+
+	return Park(ctx, ready, maxWait)
+}
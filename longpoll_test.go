@@ -0,0 +1,95 @@
+package puente_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestParkTimesOutOnClock(t *testing.T) {
+	clock := puentetest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	logger := &puentetest.Logger{}
+	m := puente.NewWithLogger("app", logger, puente.WithClock(clock))
+
+	result := make(chan error, 1)
+	h := m.Logging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result <- puente.Park(r.Context(), make(chan struct{}), time.Minute)
+	}))
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// The wall clock never reaches a minute, only the fake clock does
+	for {
+		select {
+		case err := <-result:
+			if !errors.Is(err, puente.ErrPollTimeout) {
+				t.Fatalf("Park = %v, want ErrPollTimeout", err)
+			}
+			return
+		case <-time.After(time.Millisecond):
+			clock.Advance(time.Minute)
+		}
+	}
+}
+
+func TestClockTimer(t *testing.T) {
+	clock := puentetest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name    string
+		d       time.Duration
+		advance time.Duration
+		fired   bool
+	}{
+		{"before deadline", time.Second, 999 * time.Millisecond, false},
+		{"at deadline", time.Second, time.Second, true},
+		{"past deadline", time.Second, time.Hour, true},
+		{"zero duration", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timer := clock.NewTimer(tt.d)
+			defer timer.Stop()
+
+			clock.Advance(tt.advance)
+			select {
+			case <-timer.C():
+				if !tt.fired {
+					t.Error("timer fired early")
+				}
+			default:
+				if tt.fired {
+					t.Error("timer did not fire")
+				}
+			}
+		})
+	}
+}
+
+func TestParkFuncInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"positive", time.Millisecond},
+		{"zero", 0},
+		{"negative", -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls := 0
+			cond := func() bool {
+				polls++
+				return polls > 1
+			}
+			if err := puente.ParkFunc(context.Background(), cond, tt.interval, time.Minute); err != nil {
+				t.Errorf("ParkFunc = %v, want nil", err)
+			}
+		})
+	}
+}
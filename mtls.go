@@ -0,0 +1,53 @@
+package puente
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"net/http"
+)
+
+// ClientCertIdentity middleware derives the caller identity from the verified
+// TLS client certificate. The first URI SAN (e.g. a SPIFFE ID) is preferred,
+// then the first DNS SAN, falling back to the subject common name. The
+// identity is stored under UserIDKey and the certificate fingerprint is
+// added to the access log.
+func (m *Middleware) ClientCertIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			r, requestID := m.withRequestID(r)
+
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				m.authFailure(w, r, requestID, "", errors.New("missing verified client certificate"))
+				return
+			}
+
+			cert := r.TLS.VerifiedChains[0][0]
+			identity := certIdentity(cert)
+			if identity == "" {
+				m.authFailure(w, r, requestID, "", errors.New("client certificate has no usable identity"))
+				return
+			}
+
+			fingerprint := sha256.Sum256(cert.Raw)
+			SetLogField(r.Context(), "user_id", identity)
+			SetLogField(r.Context(), "cert_fingerprint", hex.EncodeToString(fingerprint[:]))
+
+			ctx := context.WithValue(r.Context(), UserIDKey, identity)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		},
+	)
+}
+
+// certIdentity picks the identity of a client certificate
+func certIdentity(cert *x509.Certificate) string {
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,94 @@
+package puente
+
+import (
+	"context"
+	crand "crypto/rand"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// defaultByteSensitivity bounds the bytes a single request is expected to
+// add to a usage count
+const defaultByteSensitivity = 64 << 10
+
+// NoiseConfig configures the noise applied to exported usage
+type NoiseConfig struct {
+	// Epsilon is the differential privacy budget of every exported count:
+	// Laplace noise of scale sensitivity/Epsilon is added, lower values
+	// adding more noise. Zero adds no noise.
+	Epsilon float64
+	// ByteSensitivity is the bytes a single request may add to BytesIn or
+	// BytesOut, 64 KB by default. Requests have a sensitivity of 1.
+	ByteSensitivity int64
+	// Round rounds the exported counts to a multiple of it, after noise
+	Round int64
+	// Rand draws the noise, a ChaCha8 generator seeded from crypto/rand by
+	// default
+	Rand *rand.Rand
+}
+
+// noisySink applies noise to usage before handing it to the wrapped sink
+type noisySink struct {
+	sink AccountingSink
+	cfg  NoiseConfig
+	mu   sync.Mutex
+}
+
+// NoisySink returns an AccountingSink adding noise to the usage counts and
+// rounding them before flushing to sink, for usage shared outside the
+// organization. The Accounting aggregates stay exact, so internal sinks fed
+// from the same Accounting through FanOutSink see real values, and usage
+// merged back after a failed flush is never noised twice.
+func NoisySink(sink AccountingSink, cfg NoiseConfig) AccountingSink {
+	if cfg.ByteSensitivity == 0 {
+		cfg.ByteSensitivity = defaultByteSensitivity
+	}
+	if cfg.Rand == nil {
+		var seed [32]byte
+		crand.Read(seed[:])
+		cfg.Rand = rand.New(rand.NewChaCha8(seed))
+	}
+
+	return &noisySink{sink: sink, cfg: cfg}
+}
+
+// Flush implements AccountingSink
+func (s *noisySink) Flush(ctx context.Context, usage []Usage) error {
+	noisy := make([]Usage, len(usage))
+
+	s.mu.Lock()
+	for i, u := range usage {
+		noisy[i] = Usage{
+			UserID:   u.UserID,
+			Requests: s.perturb(u.Requests, 1),
+			BytesIn:  s.perturb(u.BytesIn, s.cfg.ByteSensitivity),
+			BytesOut: s.perturb(u.BytesOut, s.cfg.ByteSensitivity),
+		}
+	}
+	s.mu.Unlock()
+
+	return s.sink.Flush(ctx, noisy)
+}
+
+// perturb adds Laplace noise to n and rounds it, never going below zero
+func (s *noisySink) perturb(n, sensitivity int64) int64 {
+	v := float64(n)
+	if s.cfg.Epsilon > 0 {
+		v += s.laplace(float64(sensitivity) / s.cfg.Epsilon)
+	}
+	if s.cfg.Round > 1 {
+		v = math.Round(v/float64(s.cfg.Round)) * float64(s.cfg.Round)
+	}
+	return max(int64(math.Round(v)), 0)
+}
+
+// laplace draws from the Laplace distribution centered on 0 with scale b
+func (s *noisySink) laplace(b float64) float64 {
+	// u is uniform in (-0.5, 0.5), excluding -0.5 where the log diverges
+	u := s.cfg.Rand.Float64() - 0.5
+	for u == -0.5 {
+		u = s.cfg.Rand.Float64() - 0.5
+	}
+	return -b * math.Copysign(math.Log(1-2*math.Abs(u)), u)
+}
@@ -0,0 +1,157 @@
+package puente
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// NormalizeConfig configures request normalization
+type NormalizeConfig struct {
+	// LowercaseHost lowercases the Host of the request
+	LowercaseHost bool
+}
+
+// Normalize middleware cleans the request path before routing: duplicate
+// slashes and dot segments are removed and percent-encoding is normalized,
+// decoding unreserved characters and upper casing the rest. Encoded slashes
+// are kept. Requests attempting traversal, through encoded dot segments,
+// encoded slashes next to "..", null bytes or dot segments climbing above
+// the root, are rejected with 400, logged and published as
+// EventSuspiciousRequest. Next handlers receive a copy of the request, so
+// the access log shows the normalized path only when Normalize wraps
+// Logging.
+func (m *Middleware) Normalize(cfg NormalizeConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				raw := r.URL.EscapedPath()
+				if reason := suspiciousPath(raw); reason != "" {
+					m.rejectSuspicious(w, r, EventSuspiciousRequest, reason)
+					return
+				}
+
+				// Work on a copy, like http.StripPrefix, leaving the
+				// request of outer handlers untouched
+				r2 := new(http.Request)
+				*r2 = *r
+				r2.URL = new(url.URL)
+				*r2.URL = *r.URL
+
+				normalized := cleanPath(normalizeEscapes(raw))
+				if normalized != raw {
+					decoded, _ := url.PathUnescape(normalized)
+					r2.URL.Path = decoded
+					r2.URL.RawPath = ""
+					if r2.URL.EscapedPath() != normalized {
+						r2.URL.RawPath = normalized
+					}
+				}
+
+				if cfg.LowercaseHost {
+					r2.Host = strings.ToLower(r.Host)
+				}
+
+				next.ServeHTTP(w, r2)
+			},
+		)
+	}
+}
+
+// suspiciousPath returns why the escaped path looks like a traversal
+// attempt, or an empty string
+func suspiciousPath(raw string) string {
+	depth := 0
+	for _, segment := range strings.Split(raw, "/") {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil {
+			return "invalid percent-encoding"
+		}
+
+		switch {
+		case strings.IndexByte(decoded, 0) >= 0:
+			return "null byte"
+		case (decoded == "." || decoded == "..") && decoded != segment:
+			return "encoded dot segment"
+		case strings.Contains(decoded, "..") && strings.ContainsAny(decoded, `/\`):
+			return "encoded traversal"
+		}
+
+		switch segment {
+		case "", ".":
+		case "..":
+			if depth--; depth < 0 {
+				return "traversal above root"
+			}
+		default:
+			depth++
+		}
+	}
+	return ""
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters and upper
+// cases the hex digits of the remaining escapes
+func normalizeEscapes(raw string) string {
+	if strings.IndexByte(raw, '%') < 0 {
+		return raw
+	}
+
+	var b strings.Builder
+	b.Grow(len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '%' || i+2 >= len(raw) {
+			b.WriteByte(raw[i])
+			continue
+		}
+
+		hi, lo := unhex(raw[i+1]), unhex(raw[i+2])
+		if hi < 0 || lo < 0 {
+			b.WriteByte(raw[i])
+			continue
+		}
+
+		if c := byte(hi<<4 | lo); unreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(raw[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// cleanPath removes duplicate slashes and dot segments, keeping a trailing
+// slash
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// unreserved reports whether c is an RFC 3986 unreserved character
+func unreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// unhex returns the value of a hex digit, or -1
+func unhex(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10)
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 10)
+	}
+	return -1
+}
@@ -0,0 +1,84 @@
+package puente_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestNormalizeRejectsTraversal(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		reason string
+	}{
+		{"above root", "/a/../../etc/passwd", "traversal above root"},
+		{"encoded dot segment", "/a/%2e%2e/b", "encoded dot segment"},
+		{"encoded slash traversal", "/a/..%2Fetc", "encoded traversal"},
+		{"encoded backslash traversal", "/a/..%5Cetc", "encoded traversal"},
+		{"null byte", "/a%00.txt", "null byte"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := puente.NewWithLogger("app", &puentetest.Logger{})
+			events := m.Subscribe(puente.EventSuspiciousRequest)
+			defer m.Unsubscribe(events)
+
+			h := m.Normalize(puente.NormalizeConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler called")
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", rec.Code)
+			}
+			select {
+			case event := <-events:
+				if event.Reason != tt.reason {
+					t.Errorf("reason = %q, want %q", event.Reason, tt.reason)
+				}
+			default:
+				t.Error("no security event published")
+			}
+		})
+	}
+}
+
+func TestNormalizeCleansPath(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/a//b/./c", "/a/b/c"},
+		{"/a/b/../c/", "/a/c/"},
+		{"/%7Euser/%2fid", "/~user/%2Fid"},
+		{"/files/a%2Fb", "/files/a%2Fb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			m := puente.NewWithLogger("app", &puentetest.Logger{})
+
+			var got string
+			h := m.Normalize(puente.NormalizeConfig{LowercaseHost: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.EscapedPath()
+				if r.Host != "example.com" {
+					t.Errorf("host = %q, want it lowercased", r.Host)
+				}
+			}))
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.Host = "Example.COM"
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("path = %q, want %q", got, tt.want)
+			}
+			if r.URL.EscapedPath() != tt.target || r.Host != "Example.COM" {
+				t.Errorf("caller request modified to %s%s", r.Host, r.URL.EscapedPath())
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package oteltrace traces requests with OpenTelemetry. It is a module of
+// its own, so applications not using OpenTelemetry do not depend on it.
+package oteltrace
+
+import (
+	"net/http"
+
+	"github.com/javiertlopez/puente"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names the tracer of the package
+const instrumentation = "github.com/javiertlopez/puente/oteltrace"
+
+// Config configures request tracing
+type Config struct {
+	// TracerProvider creates the tracer, the global provider by default
+	TracerProvider trace.TracerProvider
+	// Propagator extracts the incoming trace context, the global
+	// propagator by default
+	Propagator propagation.TextMapPropagator
+}
+
+// Trace returns a middleware starting a server span for every request,
+// child of the trace context of the incoming headers. The span is named
+// after the method and route once the request is served, carries the
+// method, route, status and user_id attributes, and records the error set
+// with puente.SetError; 5xx responses mark it failed. The span is bridged
+// into puente's trace context, so puente.GetTraceID and puente.GetLogger
+// report it, and placed inside the Logging middleware trace_id and span_id
+// are added to the access log; use it instead of
+// puente.WithTracePropagation.
+func Trace(m *puente.Middleware, cfg Config) func(http.Handler) http.Handler {
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.Propagator == nil {
+		cfg.Propagator = otel.GetTextMapPropagator()
+	}
+	tracer := cfg.TracerProvider.Tracer(instrumentation)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				parentCtx := cfg.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+				ctx, span := tracer.Start(parentCtx, r.Method,
+					trace.WithSpanKind(trace.SpanKindServer),
+					trace.WithAttributes(
+						attribute.String("http.request.method", r.Method),
+						attribute.String("url.path", r.URL.Path),
+					),
+				)
+				defer span.End()
+
+				if sc := span.SpanContext(); sc.IsValid() {
+					t := puente.TraceContext{
+						TraceID: sc.TraceID().String(),
+						SpanID:  sc.SpanID().String(),
+						Sampled: sc.IsSampled(),
+						State:   sc.TraceState().String(),
+					}
+					if parent := trace.SpanContextFromContext(parentCtx); parent.IsValid() {
+						t.ParentID = parent.SpanID().String()
+					}
+					ctx = puente.ContextWithTrace(ctx, t)
+					puente.SetLogField(ctx, "trace_id", t.TraceID)
+					puente.SetLogField(ctx, "span_id", t.SpanID)
+				}
+
+				inner := r.WithContext(ctx)
+				wrapped := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+				next.ServeHTTP(wrapped, inner)
+
+				if route := m.Route(inner); route != "" {
+					span.SetName(r.Method + " " + route)
+					span.SetAttributes(attribute.String("http.route", route))
+				}
+				span.SetAttributes(attribute.Int("http.response.status_code", wrapped.status))
+
+				fields := puente.GetLogFields(ctx)
+				if userID, ok := fields["user_id"].(string); ok && userID != "" {
+					span.SetAttributes(attribute.String("user_id", userID))
+				}
+				if err, ok := fields["error"].(error); ok {
+					span.RecordError(err)
+					span.SetStatus(codes.Error, err.Error())
+				} else if wrapped.status >= http.StatusInternalServerError {
+					span.SetStatus(codes.Error, http.StatusText(wrapped.status))
+				}
+			},
+		)
+	}
+}
+
+// statusWriter records the response status
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the first status written
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original writer for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,42 @@
+package oteltrace_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/oteltrace"
+	"github.com/javiertlopez/puente/puentetest"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestTraceBridgesSpanContext(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	logger := &puentetest.Logger{}
+	m := puente.NewWithLogger("app", logger)
+	trace := oteltrace.Trace(m, oteltrace.Config{Propagator: propagation.TraceContext{}})
+
+	var got string
+	h := m.Logging(trace(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = puente.GetTraceID(r.Context())
+		puente.GetLogger(r.Context()).Info("handled", nil)
+	})))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Traceparent", "00-"+traceID+"-"+spanID+"-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got != traceID {
+		t.Errorf("GetTraceID = %q, want %q", got, traceID)
+	}
+	for _, e := range logger.Entries() {
+		if e.Fields["trace_id"] != traceID {
+			t.Errorf("entry %q: trace_id = %v, want %s", e.Message, e.Fields["trace_id"], traceID)
+		}
+	}
+}
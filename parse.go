@@ -0,0 +1,117 @@
+package puente
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxHeaderValueLength bounds the header values accepted by the parsers so
+// malformed input cannot cause pathological CPU or memory use
+const MaxHeaderValueLength = 4096
+
+var (
+	// ErrHeaderTooLong is returned for header values over MaxHeaderValueLength
+	ErrHeaderTooLong = errors.New("header value too long")
+	// ErrMalformedHeader is returned for header values that fail to parse
+	ErrMalformedHeader = errors.New("malformed header value")
+)
+
+// ParseSignature decodes a hex encoded HMAC-SHA256 signature header value,
+// stripping prefix (e.g. "sha256=") first
+func ParseSignature(value, prefix string) ([]byte, error) {
+	if len(value) > MaxHeaderValueLength {
+		return nil, ErrHeaderTooLong
+	}
+
+	value = strings.TrimPrefix(value, prefix)
+	if len(value) != hex.EncodedLen(sha256.Size) {
+		return nil, ErrMalformedHeader
+	}
+
+	signature, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, ErrMalformedHeader
+	}
+	return signature, nil
+}
+
+// ParseTimestamp parses a unix timestamp header value in seconds
+func ParseTimestamp(value string) (time.Time, error) {
+	// 19 digits hold any positive int64
+	if len(value) == 0 || len(value) > 19 {
+		return time.Time{}, ErrMalformedHeader
+	}
+
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || unix < 0 {
+		return time.Time{}, ErrMalformedHeader
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// ParseForwardedFor returns the for= addresses of an RFC 7239 Forwarded
+// header value, nearest hop last, without ports or brackets. Obfuscated and
+// unknown identifiers are returned as is.
+func ParseForwardedFor(value string) []string {
+	if len(value) > MaxHeaderValueLength {
+		return nil
+	}
+
+	var hops []string
+	for _, element := range strings.Split(value, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			key, node, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || !strings.EqualFold(key, "for") {
+				continue
+			}
+
+			node = strings.Trim(node, `"`)
+			if strings.HasPrefix(node, "[") {
+				// [IPv6]:port
+				if end := strings.IndexByte(node, ']'); end > 0 {
+					node = node[1:end]
+				}
+			} else if host, _, err := net.SplitHostPort(node); err == nil {
+				node = host
+			}
+			hops = append(hops, node)
+		}
+	}
+	return hops
+}
+
+// ParseTraceparent parses a W3C Trace Context traceparent header value into
+// the trace ID, the caller's span ID, as SpanID, and its sampling decision
+func ParseTraceparent(value string) (TraceContext, error) {
+	if len(value) > MaxHeaderValueLength {
+		return TraceContext{}, ErrHeaderTooLong
+	}
+
+	value = strings.TrimSpace(value)
+	// version-traceid-parentid-flags, future versions may append fields
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return TraceContext{}, ErrMalformedHeader
+	}
+
+	version := value[:2]
+	if !isLowerHex(version) || version == "ff" {
+		return TraceContext{}, ErrMalformedHeader
+	}
+	if version == "00" && len(value) != 55 || len(value) > 55 && value[55] != '-' {
+		return TraceContext{}, ErrMalformedHeader
+	}
+
+	traceID, spanID, flags := value[3:35], value[36:52], value[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) ||
+		allZero(traceID) || allZero(spanID) {
+		return TraceContext{}, ErrMalformedHeader
+	}
+
+	sampled := unhex(flags[1])&1 == 1
+	return TraceContext{TraceID: traceID, SpanID: spanID, Sampled: sampled}, nil
+}
@@ -0,0 +1,124 @@
+package puente_test
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+)
+
+func FuzzParseSignature(f *testing.F) {
+	f.Add("sha256="+strings.Repeat("ab", 32), "sha256=")
+	f.Add(strings.Repeat("0", 64), "")
+	f.Add("sha256=zz", "sha256=")
+	f.Add("", "")
+	f.Add(strings.Repeat("a", puente.MaxHeaderValueLength+1), "")
+
+	f.Fuzz(func(t *testing.T, value, prefix string) {
+		signature, err := puente.ParseSignature(value, prefix)
+		if err != nil {
+			if signature != nil {
+				t.Fatalf("ParseSignature(%q) returned %x with error %v", value, signature, err)
+			}
+			return
+		}
+
+		if len(signature) != 32 {
+			t.Fatalf("ParseSignature(%q) returned %d bytes", value, len(signature))
+		}
+		encoded := strings.TrimPrefix(value, prefix)
+		if !strings.EqualFold(hex.EncodeToString(signature), encoded) {
+			t.Fatalf("ParseSignature(%q) = %x, does not round trip", value, signature)
+		}
+	})
+}
+
+func FuzzParseTimestamp(f *testing.F) {
+	f.Add("1700000000")
+	f.Add("0")
+	f.Add("-1")
+	f.Add("+42")
+	f.Add("9223372036854775807")
+	f.Add("99999999999999999999")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, value string) {
+		ts, err := puente.ParseTimestamp(value)
+		if err != nil {
+			return
+		}
+
+		unix, perr := strconv.ParseInt(value, 10, 64)
+		if perr != nil || unix < 0 || ts.Unix() != unix {
+			t.Fatalf("ParseTimestamp(%q) = %v, want unix %d", value, ts, unix)
+		}
+	})
+}
+
+func FuzzParseForwardedFor(f *testing.F) {
+	f.Add(`for=192.0.2.60;proto=http;by=203.0.113.43`)
+	f.Add(`for="[2001:db8:cafe::17]:4711"`)
+	f.Add(`for=192.0.2.43, for=198.51.100.17:8080`)
+	f.Add(`For="_gazonk"`)
+	f.Add(`for=unknown;;,for=`)
+	f.Add(`for="[`)
+	f.Add(strings.Repeat("for=1,", puente.MaxHeaderValueLength))
+
+	f.Fuzz(func(t *testing.T, value string) {
+		hops := puente.ParseForwardedFor(value)
+		if len(value) > puente.MaxHeaderValueLength && hops != nil {
+			t.Fatalf("ParseForwardedFor accepted a %d byte value", len(value))
+		}
+		if len(hops) > strings.Count(strings.ToLower(value), "for=") {
+			t.Fatalf("ParseForwardedFor(%q) returned %d hops", value, len(hops))
+		}
+		for _, hop := range hops {
+			if strings.ContainsAny(hop, ",;") {
+				t.Fatalf("ParseForwardedFor(%q) returned hop %q", value, hop)
+			}
+		}
+	})
+}
+
+func FuzzParseTraceparent(f *testing.F) {
+	f.Add("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	f.Add("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	f.Add("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future")
+	f.Add("ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	f.Add("00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	f.Add("00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, value string) {
+		tc, err := puente.ParseTraceparent(value)
+		if err != nil {
+			if tc != (puente.TraceContext{}) {
+				t.Fatalf("ParseTraceparent(%q) returned %+v with error %v", value, tc, err)
+			}
+			return
+		}
+
+		if len(tc.TraceID) != 32 || len(tc.SpanID) != 16 {
+			t.Fatalf("ParseTraceparent(%q) = %+v", value, tc)
+		}
+		trimmed := strings.TrimSpace(value)
+		if trimmed[3:35] != tc.TraceID || trimmed[36:52] != tc.SpanID {
+			t.Fatalf("ParseTraceparent(%q) = %+v, IDs do not match the value", value, tc)
+		}
+
+		// A version 00 value round trips, flags other than sampled aside
+		again, err := puente.ParseTraceparent(tc.Traceparent())
+		if err != nil || again != tc {
+			t.Fatalf("Traceparent() of %+v = %q does not round trip: %+v, %v", tc, tc.Traceparent(), again, err)
+		}
+	})
+}
+
+func TestParseTraceparentRejectsUppercase(t *testing.T) {
+	_, err := puente.ParseTraceparent("00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01")
+	if err != puente.ErrMalformedHeader {
+		t.Errorf("err = %v, want ErrMalformedHeader", err)
+	}
+}
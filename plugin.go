@@ -0,0 +1,58 @@
+package puente
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Factory builds a named middleware from its configuration, as decoded
+// from JSON or YAML
+type Factory func(m *Middleware, config map[string]interface{}) (func(http.Handler) http.Handler, error)
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = map[string]Factory{}
+)
+
+// Register makes a middleware factory available by name, typically from the
+// init function of the package shipping it. It panics when name is already
+// registered, like database/sql drivers.
+func Register(name string, factory Factory) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	if factory == nil {
+		panic("puente: Register factory is nil")
+	}
+	if _, dup := plugins[name]; dup {
+		panic("puente: Register called twice for plugin " + name)
+	}
+	plugins[name] = factory
+}
+
+// Plugins returns the sorted names of the registered middleware factories
+func Plugins() []string {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Plugin builds the registered middleware called name with config
+func (m *Middleware) Plugin(name string, config map[string]interface{}) (func(http.Handler) http.Handler, error) {
+	pluginsMu.RLock()
+	factory, ok := plugins[name]
+	pluginsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("puente: unknown plugin %q", name)
+	}
+	return factory(m, config)
+}
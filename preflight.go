@@ -0,0 +1,161 @@
+package puente
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultPreflightTimeout = 10 * time.Second
+
+// PreflightError lists the preflight checks that failed, by name
+type PreflightError struct {
+	Failures map[string]error
+}
+
+// Error implements error
+func (e *PreflightError) Error() string {
+	names := make([]string, 0, len(e.Failures))
+	for name := range e.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failures := make([]string, len(names))
+	for i, name := range names {
+		failures[i] = name + ": " + e.Failures[name].Error()
+	}
+	return "preflight failed: " + strings.Join(failures, "; ")
+}
+
+// Preflight runs the preflight checks concurrently, plus a certificate
+// check when CertFile is set, each bounded by PreflightTimeout. Failures
+// are logged one by one and returned as a *PreflightError. Run calls it
+// before binding any listener.
+func (s *Server) Preflight(ctx context.Context) error {
+	checks := s.cfg.PreflightChecks
+	if s.cfg.CertFile != "" {
+		checks = append([]Check{CertificateCheck(s.cfg.CertFile, s.cfg.KeyFile)}, checks...)
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+
+	timeout := s.cfg.PreflightTimeout
+	if timeout == 0 {
+		timeout = defaultPreflightTimeout
+	}
+
+	var mu sync.Mutex
+	failures := map[string]error{}
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.WithValue(ctx, loggerKey, s.m), timeout)
+			defer cancel()
+
+			start := s.m.now()
+			err := c.Check(ctx)
+			fields := Fields{
+				"app":      s.m.app,
+				"check":    c.Name,
+				"duration": s.m.since(start),
+			}
+			if err == nil {
+				s.m.log(DebugLevel, "Preflight check passed", fields)
+				return
+			}
+
+			fields["error"] = err
+			s.m.log(ErrorLevel, "Preflight check failed", fields)
+			mu.Lock()
+			failures[c.Name] = err
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return &PreflightError{Failures: failures}
+	}
+	return nil
+}
+
+// CertificateCheck verifies that the key pair loads, matches and that the
+// certificate is currently valid, on the middleware clock when run by
+// Preflight
+func CertificateCheck(certFile, keyFile string) Check {
+	return Check{
+		Name: "tls",
+		Check: func(ctx context.Context) error {
+			pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return err
+			}
+
+			cert, err := x509.ParseCertificate(pair.Certificate[0])
+			if err != nil {
+				return err
+			}
+			now := clockFrom(ctx).Now()
+			if now.Before(cert.NotBefore) {
+				return fmt.Errorf("certificate %s not valid before %s", certFile, cert.NotBefore.Format(time.RFC3339))
+			}
+			if now.After(cert.NotAfter) {
+				return fmt.Errorf("certificate %s expired at %s", certFile, cert.NotAfter.Format(time.RFC3339))
+			}
+			return nil
+		},
+	}
+}
+
+// HTTPCheck verifies that a GET of url, such as a JWKS document or an
+// upstream health endpoint, answers 2xx
+func HTTPCheck(name, url string) Check {
+	return Check{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return fmt.Errorf("GET %s: %s", url, resp.Status)
+			}
+			return nil
+		},
+	}
+}
+
+// DialCheck verifies that a connection to a store such as Redis or a
+// database can be opened
+func DialCheck(name, network, addr string) Check {
+	return Check{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, network, addr)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		},
+	}
+}
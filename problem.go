@@ -0,0 +1,65 @@
+package puente
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is an RFC 7807 problem details document
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	Succeeded *int        `json:"succeeded,omitempty"`
+	Failed    *int        `json:"failed,omitempty"`
+	Errors    []ItemError `json:"errors,omitempty"`
+}
+
+// ItemError describes the failure of a single item of a multi-item request
+type ItemError struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// WriteProblem writes p as application/problem+json
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = GetRequestID(r.Context())
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// WritePartial reports a partially successful multi-item request with a
+// 207 Multi-Status problem document listing the failed items. The access
+// log gets partial_failure, items_succeeded and items_failed fields.
+func WritePartial(w http.ResponseWriter, r *http.Request, succeeded int, failures []ItemError) {
+	failed := len(failures)
+
+	SetLogField(r.Context(), "partial_failure", failed > 0)
+	SetLogField(r.Context(), "items_succeeded", succeeded)
+	SetLogField(r.Context(), "items_failed", failed)
+
+	WriteProblem(w, r, Problem{
+		Title:     "Partial failure",
+		Status:    http.StatusMultiStatus,
+		Succeeded: &succeeded,
+		Failed:    &failed,
+		Errors:    failures,
+	})
+}
@@ -1,17 +1,81 @@
 package puente
 
-import "github.com/sirupsen/logrus"
+import (
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
 
 // Middleware holds the app name and logger
 type Middleware struct {
-	app    string
-	logger *logrus.Logger
+	app     string
+	logger  Logger
+	budget  *memoryBudget
+	level   func(status int) Level
+	mapping FieldMapping
+	slow    time.Duration
+	control *logControl
+	clock   Clock
+
+	scrubbers   []Scrubber
+	cardinality *cardinalityGuard
+
+	startLog   bool
+	errorStack bool
+
+	bodyLog   *BodyLogConfig
+	headerLog *headerLog
+	queryLog  *queryLog
+	skip      *skipPaths
+	commonLog *commonLog
+	logFields func(r *http.Request, w ResponseInfo) Fields
+
+	requestID        func() string
+	requestIDHeader  string
+	responseIDHeader string
+	validRequestID   func(string) bool
+	requestFields    RequestField
+	routes           *RouteConfig
+	tracing          TracePropagation
+
+	trustedProxies []netip.Prefix
+	security       *securityHub
+
+	stripAuth       bool
+	authReplacement string
 }
 
-// New returns a middleware instance
-func New(app string, logger *logrus.Logger) *Middleware {
-	return &Middleware{
-		app:    app,
-		logger: logger,
+// Option configures a Middleware
+type Option func(*Middleware)
+
+// New returns a middleware instance logging through logrus
+func New(app string, logger *logrus.Logger, opts ...Option) *Middleware {
+	return NewWithLogger(app, NewLogrusLogger(logger), opts...)
+}
+
+// NewWithLogger returns a middleware instance logging through any Logger
+func NewWithLogger(app string, logger Logger, opts ...Option) *Middleware {
+	m := &Middleware{
+		app:              app,
+		logger:           logger,
+		level:            StatusLevel,
+		control:          newLogControl(),
+		clock:            realClock{},
+		requestID:        newRequestID,
+		requestIDHeader:  defaultRequestIDHeader,
+		responseIDHeader: defaultRequestIDHeader,
+		validRequestID:   ValidRequestID,
+		requestFields:    DefaultRequestFields,
+		security: &securityHub{
+			subs: map[chan SecurityEvent]map[SecurityEventType]bool{},
+		},
 	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
 }
@@ -0,0 +1,187 @@
+package puentebench
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadConfig configures a synthetic traffic run
+type LoadConfig struct {
+	// Handler is an assembled chain driven in-process. Ignored when URL is
+	// set.
+	Handler http.Handler
+	// URL is the base URL of a running server
+	URL string
+	// Mix is the weighted set of requests to send, e.g. routes with and
+	// without credentials
+	Mix []Request
+	// Concurrency is the number of workers, 1 by default
+	Concurrency int
+	// Duration of the run, 10 seconds by default
+	Duration time.Duration
+	// ErrorRate is the fraction of requests flagged for error injection,
+	// answered with a 500 by an ErrorInjector placed inside the chain
+	ErrorRate float64
+}
+
+// InjectErrorHeader flags requests for error injection
+const InjectErrorHeader = "X-Puentebench-Inject-Error"
+
+// ErrorInjector wraps the application handler of a chain under test,
+// answering 500 to the requests Load flagged for error injection so the
+// failures travel back through every middleware
+func ErrorInjector(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(InjectErrorHeader) != "" {
+				http.Error(w, "injected error", http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		},
+	)
+}
+
+// LoadReport summarizes a traffic run
+type LoadReport struct {
+	Requests    int
+	Errors      int
+	Statuses    map[int]int
+	Elapsed     time.Duration
+	Throughput  float64
+	P50         time.Duration
+	P90         time.Duration
+	P99         time.Duration
+	Max         time.Duration
+	AllocsPerOp uint64
+	BytesPerOp  uint64
+}
+
+// Load generates traffic until the configured duration elapses or ctx is
+// done. Transport failures and 5xx responses count as errors. Allocation
+// stats cover the whole process, so they are only meaningful in-process.
+func Load(ctx context.Context, cfg LoadConfig) LoadReport {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Duration == 0 {
+		cfg.Duration = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	send := cfg.sender()
+	requests := expand(cfg.Mix)
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		report    = LoadReport{Statuses: map[int]int{}}
+		wg        sync.WaitGroup
+	)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+
+			for ctx.Err() == nil {
+				tmpl := requests[rnd.Intn(len(requests))]
+				inject := cfg.ErrorRate > 0 && rnd.Float64() < cfg.ErrorRate
+
+				began := time.Now()
+				status := send(tmpl, inject)
+				latency := time.Since(began)
+
+				mu.Lock()
+				latencies = append(latencies, latency)
+				report.Statuses[status]++
+				if status == 0 || status >= http.StatusInternalServerError {
+					report.Errors++
+				}
+				mu.Unlock()
+			}
+		}(int64(i) + start.UnixNano())
+	}
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	report.Requests = len(latencies)
+	if report.Requests == 0 {
+		return report
+	}
+
+	report.Throughput = float64(report.Requests) / report.Elapsed.Seconds()
+	report.AllocsPerOp = (after.Mallocs - before.Mallocs) / uint64(report.Requests)
+	report.BytesPerOp = (after.TotalAlloc - before.TotalAlloc) / uint64(report.Requests)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.P50 = percentile(latencies, 0.50)
+	report.P90 = percentile(latencies, 0.90)
+	report.P99 = percentile(latencies, 0.99)
+	report.Max = latencies[len(latencies)-1]
+
+	return report
+}
+
+// sender returns the function performing a single request, returning its
+// status or 0 on transport failure
+func (cfg LoadConfig) sender() func(tmpl Request, inject bool) int {
+	if cfg.URL != "" {
+		client := &http.Client{}
+		return func(tmpl Request, inject bool) int {
+			req, err := http.NewRequest(tmpl.Method, cfg.URL+tmpl.Path, bytes.NewReader(tmpl.Body))
+			if err != nil {
+				return 0
+			}
+			for k, v := range tmpl.Header {
+				req.Header[k] = v
+			}
+			if inject {
+				req.Header.Set(InjectErrorHeader, "1")
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return 0
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+	}
+
+	return func(tmpl Request, inject bool) int {
+		r := httptest.NewRequest(tmpl.Method, tmpl.Path, bytes.NewReader(tmpl.Body))
+		for k, v := range tmpl.Header {
+			r.Header[k] = v
+		}
+		if inject {
+			r.Header.Set(InjectErrorHeader, "1")
+		}
+
+		w := httptest.NewRecorder()
+		cfg.Handler.ServeHTTP(w, r)
+		return w.Code
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
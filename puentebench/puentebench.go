@@ -0,0 +1,119 @@
+// Package puentebench measures the cost of puente middleware chains so
+// configuration changes can be checked for performance regressions.
+package puentebench
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Request is a weighted request template of a traffic mix
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+	Weight int
+}
+
+// Stage is a named middleware of the measured chain
+type Stage struct {
+	Name       string
+	Middleware func(http.Handler) http.Handler
+}
+
+// Result is the cost attributed to a single stage
+type Result struct {
+	Name        string
+	NsPerOp     int64
+	AllocsPerOp int64
+	BytesPerOp  int64
+}
+
+// discardWriter is a ResponseWriter dropping the response
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header         { return d.header }
+func (d *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardWriter) WriteHeader(int)             {}
+
+// Run benchmarks handler wrapped by stages, outermost first, against mix.
+// The cost of every stage is the difference between the chain including it
+// and the chain without it; the last result is the whole chain. Loggers
+// used by the stages should write to io.Discard to measure encoding only.
+func Run(stages []Stage, handler http.Handler, mix []Request) []Result {
+	requests := expand(mix)
+
+	results := make([]Result, 0, len(stages)+1)
+	previous := measure(handler, requests)
+	for i := len(stages) - 1; i >= 0; i-- {
+		chain := handler
+		for j := len(stages) - 1; j >= i; j-- {
+			chain = stages[j].Middleware(chain)
+		}
+
+		current := measure(chain, requests)
+		results = append(results, Result{
+			Name:        stages[i].Name,
+			NsPerOp:     current.NsPerOp() - previous.NsPerOp(),
+			AllocsPerOp: current.AllocsPerOp() - previous.AllocsPerOp(),
+			BytesPerOp:  current.AllocedBytesPerOp() - previous.AllocedBytesPerOp(),
+		})
+		previous = current
+	}
+
+	// Report outermost stage first
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+
+	return append(results, Result{
+		Name:        "total",
+		NsPerOp:     previous.NsPerOp(),
+		AllocsPerOp: previous.AllocsPerOp(),
+		BytesPerOp:  previous.AllocedBytesPerOp(),
+	})
+}
+
+// expand repeats every request of the mix according to its weight
+func expand(mix []Request) []Request {
+	var requests []Request
+	for _, req := range mix {
+		weight := req.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		for i := 0; i < weight; i++ {
+			requests = append(requests, req)
+		}
+	}
+	if len(requests) == 0 {
+		requests = []Request{{Method: http.MethodGet, Path: "/"}}
+	}
+	return requests
+}
+
+// measure benchmarks h cycling through requests
+func measure(h http.Handler, requests []Request) testing.BenchmarkResult {
+	return testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		w := &discardWriter{header: http.Header{}}
+
+		for i := 0; i < b.N; i++ {
+			tmpl := requests[i%len(requests)]
+			r := httptest.NewRequest(tmpl.Method, tmpl.Path, bytes.NewReader(tmpl.Body))
+			for k, v := range tmpl.Header {
+				r.Header[k] = v
+			}
+
+			for k := range w.header {
+				delete(w.header, k)
+			}
+			h.ServeHTTP(w, r)
+		}
+	})
+}
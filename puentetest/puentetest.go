@@ -0,0 +1,194 @@
+// Package puentetest provides helpers for testing applications assembled
+// with puente: an in-memory Logger capturing every entry, a fake upstream
+// echoing the requests it receives, a manually advanced Clock and checks of
+// the log contract published by puente.Schema.
+package puentetest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/javiertlopez/puente"
+)
+
+// Entry is a captured log entry
+type Entry struct {
+	Level   puente.Level
+	Message string
+	Fields  puente.Fields
+}
+
+// Logger is a puente.Logger keeping every entry in memory
+type Logger struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Log implements puente.Logger
+func (l *Logger) Log(level puente.Level, msg string, fields puente.Fields) {
+	copied := make(puente.Fields, len(fields))
+	for k, v := range fields {
+		copied[k] = v
+	}
+
+	l.mu.Lock()
+	l.entries = append(l.entries, Entry{Level: level, Message: msg, Fields: copied})
+	l.mu.Unlock()
+}
+
+// Entries returns the captured entries
+func (l *Logger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Entry(nil), l.entries...)
+}
+
+// AccessLog returns the captured access log entries
+func (l *Logger) AccessLog() []Entry {
+	var access []Entry
+	for _, e := range l.Entries() {
+		if _, ok := e.Fields["status"]; ok && (e.Message == "" || e.Message == "Request completed") {
+			access = append(access, e)
+		}
+	}
+	return access
+}
+
+// Echo is the body returned by the fake upstream
+type Echo struct {
+	Method string              `json:"method"`
+	Path   string              `json:"path"`
+	Query  string              `json:"query"`
+	Header map[string][]string `json:"header"`
+	Body   string              `json:"body"`
+}
+
+// NewUpstream starts a fake upstream answering every request with its Echo
+// as JSON. It is closed when the test ends.
+func NewUpstream(tb testing.TB) *httptest.Server {
+	tb.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(Echo{
+				Method: r.Method,
+				Path:   r.URL.Path,
+				Query:  r.URL.RawQuery,
+				Header: r.Header,
+				Body:   string(body),
+			})
+		},
+	))
+	tb.Cleanup(upstream.Close)
+
+	return upstream
+}
+
+// Clock is a puente.Clock standing still until advanced. Its timers fire
+// when Advance or Set moves the clock past their deadline.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+// NewClock returns a Clock set to now
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now implements puente.Clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer implements puente.Clock
+func (c *Clock) NewTimer(d time.Duration) puente.Timer {
+	t := &timer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.fire()
+	c.mu.Unlock()
+}
+
+// Set moves the clock to now
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	c.now = now
+	c.fire()
+	c.mu.Unlock()
+}
+
+// fire fires the timers due at the current time, with c.mu held
+func (c *Clock) fire() {
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+	}
+	c.timers = pending
+}
+
+// timer is a puente.Timer of a Clock
+type timer struct {
+	clock    *Clock
+	c        chan time.Time
+	deadline time.Time
+}
+
+// C implements puente.Timer
+func (t *timer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop implements puente.Timer
+func (t *timer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Reset implements puente.Timer
+func (t *timer) Reset(d time.Duration) bool {
+	active := t.Stop()
+
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t.deadline = c.now.Add(d)
+	c.timers = append(c.timers, t)
+	c.fire()
+	return active
+}
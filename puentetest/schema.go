@@ -0,0 +1,163 @@
+package puentetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/javiertlopez/puente"
+)
+
+// CheckSchema reports entries that break the log contract published by
+// puente.Schema: unknown messages, missing required fields and fields of
+// the wrong type. Fields added by handlers are allowed. Entries must be
+// captured without a FieldMapping.
+func CheckSchema(tb testing.TB, entries []Entry) {
+	tb.Helper()
+
+	events := map[string]puente.EventSchema{}
+	for _, event := range puente.Schema() {
+		events[event.Message] = event
+	}
+
+	for _, e := range entries {
+		event, ok := events[e.Message]
+		if !ok {
+			tb.Errorf("log entry %q is not part of the schema", e.Message)
+			continue
+		}
+
+		for _, f := range event.Fields {
+			v, ok := e.Fields[f.Name]
+			if !ok {
+				if f.Required {
+					tb.Errorf("log entry %q (%s): missing field %q", e.Message, event.Name, f.Name)
+				}
+				continue
+			}
+			if !hasType(v, f.Type) {
+				tb.Errorf("log entry %q (%s): field %q is %T, want %s", e.Message, event.Name, f.Name, v, f.Type)
+			}
+		}
+	}
+}
+
+// AssertSchema compares puente.Schema with the golden file, failing on any
+// change to the log contract. A missing golden file is created, so the
+// first run pins the current schema.
+func AssertSchema(tb testing.TB, golden string) {
+	tb.Helper()
+
+	current, err := json.MarshalIndent(puente.Schema(), "", "  ")
+	if err != nil {
+		tb.Fatalf("encoding schema: %v", err)
+	}
+	current = append(current, '\n')
+
+	pinned, err := os.ReadFile(golden)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(golden, current, 0o644); err != nil {
+			tb.Fatalf("writing %s: %v", golden, err)
+		}
+		tb.Logf("wrote log schema to %s", golden)
+		return
+	}
+	if err != nil {
+		tb.Fatalf("reading %s: %v", golden, err)
+	}
+	if bytes.Equal(pinned, current) {
+		return
+	}
+
+	var old []puente.EventSchema
+	if err := json.Unmarshal(pinned, &old); err != nil {
+		tb.Fatalf("decoding %s: %v", golden, err)
+	}
+	for _, change := range schemaChanges(old, puente.Schema()) {
+		tb.Errorf("log schema changed: %s", change)
+	}
+	tb.Errorf("log schema differs from %s; delete it to pin the new schema", golden)
+}
+
+// schemaChanges describes the differences between two schemas
+func schemaChanges(old, current []puente.EventSchema) []string {
+	var changes []string
+
+	index := func(events []puente.EventSchema) map[string]puente.EventSchema {
+		byName := make(map[string]puente.EventSchema, len(events))
+		for _, event := range events {
+			byName[event.Name] = event
+		}
+		return byName
+	}
+	before, after := index(old), index(current)
+
+	for _, event := range old {
+		now, ok := after[event.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("event %s removed", event.Name))
+			continue
+		}
+		if now.Message != event.Message {
+			changes = append(changes, fmt.Sprintf("event %s: message %q is now %q", event.Name, event.Message, now.Message))
+		}
+
+		fields := map[string]puente.FieldSchema{}
+		for _, f := range now.Fields {
+			fields[f.Name] = f
+		}
+		for _, f := range event.Fields {
+			g, ok := fields[f.Name]
+			switch {
+			case !ok:
+				changes = append(changes, fmt.Sprintf("event %s: field %s removed", event.Name, f.Name))
+			case g != f:
+				changes = append(changes, fmt.Sprintf("event %s: field %s is now %+v, was %+v", event.Name, f.Name, g, f))
+			}
+			delete(fields, f.Name)
+		}
+		for _, f := range now.Fields {
+			if _, ok := fields[f.Name]; ok {
+				changes = append(changes, fmt.Sprintf("event %s: field %s added", event.Name, f.Name))
+			}
+		}
+	}
+
+	for _, event := range current {
+		if _, ok := before[event.Name]; !ok {
+			changes = append(changes, fmt.Sprintf("event %s added", event.Name))
+		}
+	}
+
+	return changes
+}
+
+// hasType reports whether a captured field value encodes as t
+func hasType(v interface{}, t puente.FieldType) bool {
+	if _, ok := v.(error); ok {
+		return t == puente.StringField
+	}
+	if _, ok := v.(time.Duration); ok {
+		return t == puente.DurationField
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String:
+		return t == puente.StringField
+	case reflect.Bool:
+		return t == puente.BooleanField
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return t == puente.IntegerField || t == puente.NumberField || t == puente.DurationField
+	case reflect.Float32, reflect.Float64:
+		return t == puente.NumberField
+	case reflect.Map, reflect.Struct:
+		return t == puente.ObjectField
+	}
+	return false
+}
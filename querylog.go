@@ -0,0 +1,82 @@
+package puente
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// defaultScrubbedParams are always scrubbed from logged query strings
+var defaultScrubbedParams = []string{"token", "access_token", "api_key", "apikey", "password", "secret"}
+
+// QueryLogConfig configures logging of the raw query string
+type QueryLogConfig struct {
+	// Scrub lists additional parameter names whose values are replaced, on
+	// top of token, access_token, api_key, apikey, password and secret.
+	// Names are matched case-insensitively.
+	Scrub []string
+	// ScrubPatterns scrubs every parameter whose name matches
+	ScrubPatterns []*regexp.Regexp
+}
+
+// queryLog is the compiled query logging configuration
+type queryLog struct {
+	scrub    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// WithQueryLogging adds the query string to the access log as query, with
+// the values of sensitive parameters scrubbed
+func WithQueryLogging(cfg QueryLogConfig) Option {
+	q := &queryLog{
+		scrub:    map[string]bool{},
+		patterns: cfg.ScrubPatterns,
+	}
+	for _, name := range append(defaultScrubbedParams, cfg.Scrub...) {
+		q.scrub[strings.ToLower(name)] = true
+	}
+
+	return func(m *Middleware) {
+		m.queryLog = q
+	}
+}
+
+// addQuery adds the scrubbed raw query to fields
+func (q *queryLog) addQuery(fields Fields, rawQuery string) {
+	if q == nil || rawQuery == "" {
+		return
+	}
+
+	fields["query"] = q.scrubQuery(rawQuery)
+}
+
+// scrubQuery replaces the values of sensitive parameters, keeping the
+// order and encoding of the others
+func (q *queryLog) scrubQuery(rawQuery string) string {
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, _, hasValue := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+
+		if hasValue && q.scrubbed(name) {
+			params[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// scrubbed reports whether the value of the named parameter must be hidden
+func (q *queryLog) scrubbed(name string) bool {
+	if q.scrub[strings.ToLower(name)] {
+		return true
+	}
+	for _, pattern := range q.patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
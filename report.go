@@ -0,0 +1,96 @@
+package puente
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencySamples bounds the latencies kept for the shutdown report
+const latencySamples = 10000
+
+// serverStats aggregates the requests served by the public and Unix socket
+// listeners of a Server for its shutdown report
+type serverStats struct {
+	clock        Clock
+	started      time.Time
+	requests     atomic.Int64
+	clientErrors atomic.Int64
+	serverErrors atomic.Int64
+	inFlight     atomic.Int64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+// wrap counts the requests served by handler
+func (st *serverStats) wrap(handler http.Handler) http.Handler {
+	if handler == nil {
+		return nil
+	}
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			start := st.clock.Now()
+			st.inFlight.Add(1)
+			defer st.inFlight.Add(-1)
+
+			wrapped := newResponseWriter(w)
+			handler.ServeHTTP(wrapped, r)
+
+			st.requests.Add(1)
+			switch {
+			case wrapped.statusCode >= 500:
+				st.serverErrors.Add(1)
+			case wrapped.statusCode >= 400:
+				st.clientErrors.Add(1)
+			}
+			st.observe(st.clock.Now().Sub(start))
+		},
+	)
+}
+
+// observe keeps a latency, replacing the oldest once the samples are full
+func (st *serverStats) observe(d time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if len(st.latencies) < latencySamples {
+		st.latencies = append(st.latencies, d)
+		return
+	}
+	st.latencies[st.next] = d
+	st.next = (st.next + 1) % latencySamples
+}
+
+// p95 returns the 95th percentile of the sampled latencies
+func (st *serverStats) p95() time.Duration {
+	st.mu.Lock()
+	sorted := append([]time.Duration(nil), st.latencies...)
+	st.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*95+99)/100-1]
+}
+
+// report logs the shutdown summary of the server: uptime, requests and
+// errors served, p95 latency of the latest requests, and the requests still
+// in flight when the listeners started closing
+func (s *Server) report(drained int64, drainDuration time.Duration) {
+	s.m.log(InfoLevel, "Server stopped", Fields{
+		"app":            s.m.app,
+		"uptime":         s.m.since(s.stats.started),
+		"requests":       s.stats.requests.Load(),
+		"client_errors":  s.stats.clientErrors.Load(),
+		"server_errors":  s.stats.serverErrors.Load(),
+		"p95_latency":    s.stats.p95(),
+		"drained":        drained,
+		"drain_duration": drainDuration,
+	})
+}
@@ -0,0 +1,41 @@
+package puente
+
+import "net/http"
+
+// RequestField selects optional request attributes for the access log
+type RequestField uint
+
+// Optional access log request fields
+const (
+	FieldUserAgent RequestField = 1 << iota
+	FieldReferer
+	FieldHost
+	FieldProto
+
+	// DefaultRequestFields are logged unless WithRequestFields says otherwise
+	DefaultRequestFields = FieldUserAgent | FieldReferer | FieldHost | FieldProto
+)
+
+// WithRequestFields selects which of user_agent, referer, host and proto
+// are added to the access log. Pass 0 to disable all of them.
+func WithRequestFields(fields RequestField) Option {
+	return func(m *Middleware) {
+		m.requestFields = fields
+	}
+}
+
+// addRequestFields adds the selected request attributes to fields
+func (m *Middleware) addRequestFields(fields Fields, r *http.Request) {
+	if m.requestFields&FieldUserAgent != 0 && r.UserAgent() != "" {
+		fields["user_agent"] = r.UserAgent()
+	}
+	if m.requestFields&FieldReferer != 0 && r.Referer() != "" {
+		fields["referer"] = r.Referer()
+	}
+	if m.requestFields&FieldHost != 0 && r.Host != "" {
+		fields["host"] = r.Host
+	}
+	if m.requestFields&FieldProto != 0 {
+		fields["proto"] = r.Proto
+	}
+}
@@ -0,0 +1,133 @@
+package puente
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// newRequestID returns a random 128-bit hex encoded identifier
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithIDGenerator sets the function generating request IDs, such as ULID
+// for lexicographically ordered IDs. IDs are random 128-bit hex strings by
+// default.
+func WithIDGenerator(generate func() string) Option {
+	return func(m *Middleware) {
+		m.requestID = generate
+	}
+}
+
+// WithRequestIDSequence generates reproducible request IDs, prefix
+// followed by a zero padded sequence number starting at 1, for golden file
+// log tests and recorded traffic replays
+func WithRequestIDSequence(prefix string) Option {
+	return func(m *Middleware) {
+		var seq atomic.Uint64
+		m.requestID = func() string {
+			return fmt.Sprintf("%s%08d", prefix, seq.Add(1))
+		}
+	}
+}
+
+// WithRequestIDSeed generates request IDs shaped like the random ones but
+// drawn from a generator seeded with seed, so runs are reproducible
+func WithRequestIDSeed(seed uint64) Option {
+	return func(m *Middleware) {
+		var mu sync.Mutex
+		rng := mathrand.New(mathrand.NewPCG(seed, seed))
+		m.requestID = func() string {
+			var b [16]byte
+			mu.Lock()
+			binary.BigEndian.PutUint64(b[:8], rng.Uint64())
+			binary.BigEndian.PutUint64(b[8:], rng.Uint64())
+			mu.Unlock()
+			return hex.EncodeToString(b[:])
+		}
+	}
+}
+
+// defaultRequestIDHeader carries request IDs set by load balancers
+const defaultRequestIDHeader = "X-Request-ID"
+
+// WithRequestIDHeader sets the incoming header whose request ID is reused,
+// X-Request-ID by default. An empty name always generates the ID.
+func WithRequestIDHeader(name string) Option {
+	return func(m *Middleware) {
+		m.requestIDHeader = name
+	}
+}
+
+// WithResponseRequestIDHeader sets the response header the Logging
+// middleware echoes the request ID in, X-Request-ID by default, so clients
+// can quote it. An empty name disables the echo.
+func WithResponseRequestIDHeader(name string) Option {
+	return func(m *Middleware) {
+		m.responseIDHeader = name
+	}
+}
+
+// maxRequestIDLength bounds request IDs accepted from the header
+const maxRequestIDLength = 128
+
+// ValidRequestID reports whether id is safe to reuse from an untrusted
+// header: at most 128 characters of letters, digits and -_.:/+=@, so it
+// can't break log lines or inflate them
+func ValidRequestID(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("-_.:/+=@", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestIDValidation sets the check request IDs from the header must
+// pass to be reused, ValidRequestID by default. Rejected IDs are replaced
+// by a generated one. A nil validate trusts the header as is.
+func WithRequestIDValidation(validate func(id string) bool) Option {
+	return func(m *Middleware) {
+		m.validRequestID = validate
+	}
+}
+
+// withRequestID resolves the request ID from the context, then from the
+// request ID header, generating one when absent or invalid, and returns the
+// request carrying it
+func (m *Middleware) withRequestID(r *http.Request) (*http.Request, string) {
+	if id := GetRequestID(r.Context()); id != "" {
+		return r, id
+	}
+
+	var id string
+	if m.requestIDHeader != "" {
+		id = strings.TrimSpace(r.Header.Get(m.requestIDHeader))
+	}
+	if id != "" && m.validRequestID != nil && !m.validRequestID(id) {
+		id = ""
+	}
+	if id == "" {
+		id = m.requestID()
+	}
+	return r.WithContext(context.WithValue(r.Context(), RequestIDKey, id)), id
+}
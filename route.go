@@ -0,0 +1,101 @@
+package puente
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// RouteConfig configures logging of the matched route template
+type RouteConfig struct {
+	// Pattern returns the route matched for the request handed to the next
+	// handler, when the router exposes it after serving, e.g. for chi
+	//
+	//	func(r *http.Request) string { return chi.RouteContext(r.Context()).RoutePattern() }
+	//
+	// When nil, the http.ServeMux pattern (Request.Pattern) is used. It is
+	// only seen when the mux is handed the request Logging passed down;
+	// middleware in between copying the request, such as BasicAuth, hide
+	// it, so wrap the mux with RecordRoute then. Routers creating their own
+	// request, such as gorilla/mux, report the route from inside with
+	// SetRoute instead.
+	Pattern func(r *http.Request) string
+	// ReplacePath logs the route in the path field instead of the concrete
+	// path, bounding the cardinality of logs and derived metrics
+	ReplacePath bool
+}
+
+// WithRoutes logs the matched route template, e.g. /users/{id}, as the
+// route field
+func WithRoutes(cfg RouteConfig) Option {
+	return func(m *Middleware) {
+		m.routes = &cfg
+	}
+}
+
+// SetRoute records the route template matched for the current request,
+// taking precedence over RouteConfig.Pattern
+func SetRoute(ctx context.Context, route string) {
+	SetLogField(ctx, "route", route)
+}
+
+// RecordRoute wraps a router setting Request.Pattern, such as
+// http.ServeMux, recording the pattern it matched with SetRoute unless the
+// handler set the route itself. Placed right around the router, the route
+// is logged whatever middleware sits between Logging and it.
+func RecordRoute(router http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			router.ServeHTTP(w, r)
+
+			if r.Pattern == "" {
+				return
+			}
+			if _, ok := GetLogFields(r.Context())["route"]; !ok {
+				SetRoute(r.Context(), patternRoute(r.Pattern))
+			}
+		},
+	)
+}
+
+// route returns the route matched for r, the request passed to the next
+// handler, and adds it to fields
+func (m *Middleware) route(fields Fields, r *http.Request) string {
+	if m.routes == nil {
+		return ""
+	}
+
+	route := m.matchedRoute(fields, r)
+	if route != "" {
+		fields["route"] = route
+	}
+	return route
+}
+
+// Route returns the route template matched for r once it has been served,
+// as logged in the route field, or "" when unknown
+func (m *Middleware) Route(r *http.Request) string {
+	return m.matchedRoute(GetLogFields(r.Context()), r)
+}
+
+// matchedRoute returns the route recorded in fields with SetRoute, reported
+// by RouteConfig.Pattern or matched by http.ServeMux
+func (m *Middleware) matchedRoute(fields Fields, r *http.Request) string {
+	route, _ := fields["route"].(string)
+	if route == "" && m.routes != nil && m.routes.Pattern != nil {
+		route = m.routes.Pattern(r)
+	}
+	if route == "" {
+		route = patternRoute(r.Pattern)
+	}
+	return route
+}
+
+// patternRoute strips the method of a ServeMux pattern,
+// "[METHOD ][HOST]/PATH"
+func patternRoute(pattern string) string {
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		return strings.TrimLeft(pattern[i:], " ")
+	}
+	return pattern
+}
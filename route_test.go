@@ -0,0 +1,59 @@
+package puente_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestRouteBehindBasicAuth(t *testing.T) {
+	logger := &puentetest.Logger{}
+	m := puente.NewWithLogger("app", logger, puente.WithRoutes(puente.RouteConfig{ReplacePath: true}))
+	verifier := puente.NewMemoryCredentials(map[string]string{"ana": "secret"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		puente.SetRoute(r.Context(), "/orders/:id")
+	})
+
+	// BasicAuth copies the request before it reaches the mux
+	h := m.Logging(m.BasicAuth(verifier)(puente.RecordRoute(mux)))
+
+	for _, tt := range []struct {
+		path  string
+		route string
+	}{
+		{"/users/42", "/users/{id}"},
+		{"/orders/7", "/orders/:id"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.SetBasicAuth("ana", "secret")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		entries := logger.AccessLog()
+		fields := entries[len(entries)-1].Fields
+		if fields["route"] != tt.route {
+			t.Errorf("%s: route = %v, want %s", tt.path, fields["route"], tt.route)
+		}
+		if fields["path"] != tt.route {
+			t.Errorf("%s: path = %v, want the route %s", tt.path, fields["path"], tt.route)
+		}
+	}
+}
+
+func TestRouteFromDirectMux(t *testing.T) {
+	logger := &puentetest.Logger{}
+	m := puente.NewWithLogger("app", logger, puente.WithRoutes(puente.RouteConfig{}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	m.Logging(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	if got := logger.AccessLog()[0].Fields["route"]; got != "/users/{id}" {
+		t.Errorf("route = %v, want /users/{id}", got)
+	}
+}
@@ -0,0 +1,214 @@
+package puente
+
+import "encoding/json"
+
+// FieldType is the JSON type of a log field as written by the JSON and
+// logrus JSON loggers
+type FieldType string
+
+// Field types. Durations are integers in nanoseconds and errors strings.
+const (
+	StringField   FieldType = "string"
+	IntegerField  FieldType = "integer"
+	NumberField   FieldType = "number"
+	BooleanField  FieldType = "boolean"
+	ObjectField   FieldType = "object"
+	DurationField FieldType = "duration"
+)
+
+// FieldSchema describes a field of a log event
+type FieldSchema struct {
+	Name     string    `json:"name"`
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required,omitempty"`
+}
+
+// EventSchema describes a log event type, identified by its message. The
+// access log entry has an empty message, "Request completed" with
+// WithRequestStartLog.
+type EventSchema struct {
+	Name    string        `json:"name"`
+	Message string        `json:"message"`
+	Fields  []FieldSchema `json:"fields"`
+}
+
+// required and optional build field schemas
+func required(name string, t FieldType) FieldSchema {
+	return FieldSchema{Name: name, Type: t, Required: true}
+}
+
+func optional(name string, t FieldType) FieldSchema {
+	return FieldSchema{Name: name, Type: t}
+}
+
+// accessFields are the fields of the access log entry, before any field
+// mapping. Handlers may add more with SetLogField.
+var accessFields = []FieldSchema{
+	required("app", StringField),
+	required("request_id", StringField),
+	required("client_ip", StringField),
+	required("status", IntegerField),
+	required("bytes", IntegerField),
+	required("method", StringField),
+	required("path", StringField),
+	required("duration", DurationField),
+	optional("user_id", StringField),
+	optional("user_agent", StringField),
+	optional("referer", StringField),
+	optional("host", StringField),
+	optional("proto", StringField),
+	optional("route", StringField),
+	optional("trace_id", StringField),
+	optional("span_id", StringField),
+	optional("query", StringField),
+	optional("request_headers", ObjectField),
+	optional("response_headers", ObjectField),
+	optional("request_body", StringField),
+	optional("request_body_truncated", BooleanField),
+	optional("response_body", StringField),
+	optional("response_body_truncated", BooleanField),
+	optional("buffer_skipped", BooleanField),
+	optional("slow", BooleanField),
+	optional("slow_client", BooleanField),
+	optional("error", StringField),
+	optional("error_type", StringField),
+	optional("stack", StringField),
+	optional("context_error", StringField),
+	optional("context_cause", StringField),
+	optional("parked_duration", DurationField),
+	optional("processing_duration", DurationField),
+	optional("handler_duration", DurationField),
+	optional("middleware_duration", DurationField),
+	optional("upstream_duration", DurationField),
+	optional("cert_fingerprint", StringField),
+	optional("partial_failure", BooleanField),
+	optional("items_succeeded", IntegerField),
+	optional("items_failed", IntegerField),
+}
+
+// Schema returns every log event type the package emits, so consumers can
+// pin the log contract. Fields are named as emitted, before any
+// FieldMapping.
+func Schema() []EventSchema {
+	app := required("app", StringField)
+	requestID := required("request_id", StringField)
+	method := required("method", StringField)
+	path := required("path", StringField)
+	errField := required("error", StringField)
+	listener := required("listener", StringField)
+
+	return []EventSchema{
+		{"access", "", accessFields},
+		{"request_completed", "Request completed", accessFields},
+		{"request_received", "Request received", []FieldSchema{app, requestID, required("client_ip", StringField), method, path}},
+		{"authentication_failed", "Authentication failed", []FieldSchema{app, requestID, method, path, errField, optional("username", StringField)}},
+		{"authorization_denied", "Authorization denied", []FieldSchema{app, requestID, method, path, required("user_id", StringField)}},
+		{"policy_evaluation_failed", "Policy evaluation failed", []FieldSchema{app, requestID, method, path, required("user_id", StringField), errField}},
+		{"suspicious_request_rejected", "Suspicious request rejected", []FieldSchema{app, requestID, required("client_ip", StringField), method, path, required("event", StringField), required("reason", StringField)}},
+		{"middleware_decisions_differ", "Middleware decisions differ", []FieldSchema{app, requestID, required("middleware", StringField), required("enforced", StringField), required("evaluated", StringField)}},
+		{"connection_dropped", "Connection dropped for slowness", []FieldSchema{app, required("remote_addr", StringField), required("reason", StringField), optional("request_id", StringField), optional("method", StringField), optional("path", StringField), optional("listener", StringField), optional("duration", DurationField)}},
+		{"idle_connection_closed", "Idle connection closed", []FieldSchema{app, listener, required("remote_addr", StringField), required("duration", DurationField)}},
+		{"log_settings_changed", "Log settings changed", []FieldSchema{app, requestID, required("log_level", StringField), required("sample_rate", NumberField)}},
+		{"high_cardinality_field", "High cardinality field", []FieldSchema{app, required("field", StringField), required("reason", StringField), required("limit", IntegerField)}},
+		{"job_updated", "Job updated", []FieldSchema{app, requestID, required("job_id", StringField), required("job_status", StringField), optional("error", StringField)}},
+		{"job_save_failed", "Failed to save job", []FieldSchema{app, requestID, required("job_id", StringField), required("job_status", StringField), errField}},
+		{"event_publish_failed", "Failed to publish event", []FieldSchema{app, requestID, required("event_id", StringField), required("event", StringField), errField}},
+		{"webhook_delivered", "Webhook delivered", webhookFields(false)},
+		{"webhook_retrying", "Webhook delivery failed, retrying", webhookFields(true)},
+		{"webhook_dead_lettered", "Webhook dead-lettered", webhookFields(true)},
+		{"concurrency_limit_exceeded", "Concurrency limit exceeded", []FieldSchema{app, requestID, method, path, required("identity", StringField), required("group", StringField), required("limit", IntegerField)}},
+		{"large_payload", "Large payload", []FieldSchema{app, requestID, method, path, required("direction", StringField), required("bytes", IntegerField), required("limit", IntegerField), optional("route", StringField)}},
+		{"accounting_flush_failed", "Failed to flush accounting", []FieldSchema{app, required("users", IntegerField), errField}},
+		{"audit_batch_failed", "Audit batch failed", auditFields()},
+		{"audit_batch_dropped", "Audit batch dropped", auditFields()},
+		{"preflight_check_passed", "Preflight check passed", []FieldSchema{app, required("check", StringField), required("duration", DurationField)}},
+		{"preflight_check_failed", "Preflight check failed", []FieldSchema{app, required("check", StringField), required("duration", DurationField), errField}},
+		{"listener_started", "Listener started", []FieldSchema{app, listener, required("addr", StringField)}},
+		{"listener_failed", "Listener failed", []FieldSchema{app, errField}},
+		{"listener_stopped", "Listener stopped", []FieldSchema{app, listener}},
+		{"listener_shutdown_failed", "Listener shutdown failed", []FieldSchema{app, listener, errField}},
+		{"systemd_notify_failed", "Failed to notify systemd", []FieldSchema{app, errField}},
+		{"draining", "Draining", []FieldSchema{app, required("drain_delay", DurationField)}},
+		{"config_reloaded", "Config reloaded", []FieldSchema{app, required("file", StringField)}},
+		{"config_reload_failed", "Config reload failed", []FieldSchema{app, required("file", StringField), errField}},
+		{"server_stopped", "Server stopped", []FieldSchema{
+			app,
+			required("uptime", DurationField),
+			required("requests", IntegerField),
+			required("client_errors", IntegerField),
+			required("server_errors", IntegerField),
+			required("p95_latency", DurationField),
+			required("drained", IntegerField),
+			required("drain_duration", DurationField),
+		}},
+	}
+}
+
+func webhookFields(failed bool) []FieldSchema {
+	fields := []FieldSchema{
+		required("app", StringField),
+		required("request_id", StringField),
+		required("delivery_id", StringField),
+		required("event", StringField),
+		required("url", StringField),
+		required("attempt", IntegerField),
+		required("status", IntegerField),
+	}
+	if failed {
+		fields = append(fields, required("error", StringField))
+	}
+	return fields
+}
+
+func auditFields() []FieldSchema {
+	return []FieldSchema{
+		required("app", StringField),
+		required("events", IntegerField),
+		required("attempts", IntegerField),
+		required("error", StringField),
+	}
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document validating the
+// entries of the JSON loggers against Schema
+func JSONSchema() ([]byte, error) {
+	var variants []interface{}
+	defs := map[string]interface{}{}
+
+	for _, event := range Schema() {
+		properties := map[string]interface{}{
+			"msg":   map[string]interface{}{"const": event.Message},
+			"level": map[string]interface{}{"enum": []string{"debug", "info", "warning", "error"}},
+			"time":  map[string]interface{}{"type": "string", "format": "date-time"},
+		}
+		requiredFields := []string{"level", "time"}
+		if event.Message != "" {
+			requiredFields = append(requiredFields, "msg")
+		}
+
+		for _, f := range event.Fields {
+			t := string(f.Type)
+			if f.Type == DurationField {
+				t = string(IntegerField)
+			}
+			properties[f.Name] = map[string]interface{}{"type": t}
+			if f.Required {
+				requiredFields = append(requiredFields, f.Name)
+			}
+		}
+
+		defs[event.Name] = map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   requiredFields,
+		}
+		variants = append(variants, map[string]interface{}{"$ref": "#/$defs/" + event.Name})
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "puente log entry",
+		"$defs":   defs,
+		"anyOf":   variants,
+	}, "", "  ")
+}
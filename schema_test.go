@@ -0,0 +1,68 @@
+package puente_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestLogSchemaGolden(t *testing.T) {
+	puentetest.AssertSchema(t, "testdata/log_schema.json")
+}
+
+func TestLoggingMatchesSchema(t *testing.T) {
+	logger := &puentetest.Logger{}
+	m := puente.NewWithLogger("app", logger,
+		puente.WithRequestStartLog(),
+		puente.WithRoutes(puente.RouteConfig{}),
+		puente.WithTracePropagation(puente.PropagateW3C),
+		puente.WithSlowRequestThreshold(time.Nanosecond),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /users/{id}", m.HandlerTiming(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			puente.SetError(r.Context(), errors.New("boom"))
+			http.Error(w, "boom", http.StatusInternalServerError)
+		},
+	)))
+	h := m.Logging(mux)
+
+	r := httptest.NewRequest(http.MethodPost, "/users/1?page=2", strings.NewReader("{}"))
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("Referer", "http://example.com/")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	entries := logger.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want the start and access log entries", len(entries))
+	}
+	puentetest.CheckSchema(t, entries)
+
+	// Every field the access log emits must be published in the schema
+	known := map[string]bool{}
+	for _, event := range puente.Schema() {
+		if event.Message == "Request completed" {
+			for _, f := range event.Fields {
+				known[f.Name] = true
+			}
+		}
+	}
+	access := logger.AccessLog()[0]
+	for k := range access.Fields {
+		if !known[k] {
+			t.Errorf("access log field %q is not part of the schema", k)
+		}
+	}
+	for _, k := range []string{"route", "trace_id", "span_id", "error", "error_type", "slow", "handler_duration", "middleware_duration", "user_agent", "referer"} {
+		if _, ok := access.Fields[k]; !ok {
+			t.Errorf("access log is missing %q", k)
+		}
+	}
+}
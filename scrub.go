@@ -0,0 +1,134 @@
+package puente
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Scrubber removes personal data from a log field value before it is
+// emitted. Scrub is called with the field key and its string value and
+// returns the value to log.
+type Scrubber interface {
+	Scrub(key, value string) string
+}
+
+// ScrubberFunc adapts a function to a Scrubber
+type ScrubberFunc func(key, value string) string
+
+// Scrub implements Scrubber
+func (f ScrubberFunc) Scrub(key, value string) string {
+	return f(key, value)
+}
+
+// WithScrubbers applies scrubbers, in order, to every string value of every
+// entry, including errors, header maps and captured bodies, and to the
+// message
+func WithScrubbers(scrubbers ...Scrubber) Option {
+	return func(m *Middleware) {
+		m.scrubbers = append(m.scrubbers, scrubbers...)
+	}
+}
+
+// RegexScrubber replaces every match of pattern with replacement, which may
+// refer to submatches as in regexp.Regexp.ReplaceAllString
+func RegexScrubber(pattern *regexp.Regexp, replacement string) Scrubber {
+	return ScrubberFunc(func(key, value string) string {
+		return pattern.ReplaceAllString(value, replacement)
+	})
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+	ssnPattern   = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+)
+
+// EmailScrubber redacts email addresses
+var EmailScrubber = RegexScrubber(emailPattern, redacted)
+
+// SSNScrubber redacts US social security numbers written as 123-45-6789
+var SSNScrubber = RegexScrubber(ssnPattern, redacted)
+
+// CardScrubber redacts payment card numbers, 13 to 19 digits optionally
+// separated by spaces or dashes that pass the Luhn check
+var CardScrubber = ScrubberFunc(func(key, value string) string {
+	return cardPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if luhn(match) {
+			return redacted
+		}
+		return match
+	})
+})
+
+// luhn reports whether the digits of s pass the Luhn checksum
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// scrub returns the message and a copy of fields with the scrubbers applied
+func (m *Middleware) scrub(msg string, fields Fields) (string, Fields) {
+	if len(m.scrubbers) == 0 {
+		return msg, fields
+	}
+
+	scrubbed := make(Fields, len(fields))
+	for k, v := range fields {
+		scrubbed[k] = m.scrubValue(k, v)
+	}
+	return m.scrubString("msg", msg), scrubbed
+}
+
+// scrubValue scrubs the strings held by a field value
+func (m *Middleware) scrubValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return m.scrubString(key, v)
+	case error:
+		return m.scrubString(key, v.Error())
+	case []string:
+		scrubbed := make([]string, len(v))
+		for i, s := range v {
+			scrubbed[i] = m.scrubString(key, s)
+		}
+		return scrubbed
+	case map[string]string:
+		scrubbed := make(map[string]string, len(v))
+		for k, s := range v {
+			scrubbed[k] = m.scrubString(key+"."+strings.ToLower(k), s)
+		}
+		return scrubbed
+	case map[string]interface{}:
+		scrubbed := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			scrubbed[k] = m.scrubValue(key+"."+k, s)
+		}
+		return scrubbed
+	case Fields:
+		return Fields(m.scrubValue(key, map[string]interface{}(v)).(map[string]interface{}))
+	}
+	return value
+}
+
+// scrubString applies every scrubber to a string
+func (m *Middleware) scrubString(key, value string) string {
+	for _, s := range m.scrubbers {
+		value = s.Scrub(key, value)
+	}
+	return value
+}
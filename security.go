@@ -0,0 +1,135 @@
+package puente
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// securityBuffer is the channel capacity of every subscription
+const securityBuffer = 64
+
+// SecurityEventType identifies a kind of security event
+type SecurityEventType string
+
+// Security event types
+const (
+	// EventAuthFailure is published when BasicAuth, ClientCertIdentity or
+	// VerifySignature reject a request
+	EventAuthFailure SecurityEventType = "auth_failure"
+	// EventAccessDenied is published when Authorize denies a request
+	EventAccessDenied SecurityEventType = "access_denied"
+	// EventSuspiciousRequest is published when Normalize rejects a request
+	EventSuspiciousRequest SecurityEventType = "suspicious_request"
+	// EventInvalidHeaders is published when ValidateHeaders rejects a
+	// request
+	EventInvalidHeaders SecurityEventType = "invalid_headers"
+)
+
+// SecurityEvent describes a rejected request
+type SecurityEvent struct {
+	Type      SecurityEventType `json:"type"`
+	Time      time.Time         `json:"time"`
+	RequestID string            `json:"request_id"`
+	ClientIP  string            `json:"client_ip"`
+	UserID    string            `json:"user_id,omitempty"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Reason    string            `json:"reason"`
+}
+
+// securityHub fans security events out to subscribers
+type securityHub struct {
+	mu   sync.RWMutex
+	subs map[chan SecurityEvent]map[SecurityEventType]bool
+}
+
+// Subscribe returns a channel receiving the security events of the given
+// types, or of every type when none is given. The channel is buffered;
+// events are dropped rather than blocking requests when it is full.
+func (m *Middleware) Subscribe(types ...SecurityEventType) <-chan SecurityEvent {
+	filter := map[SecurityEventType]bool{}
+	for _, t := range types {
+		filter[t] = true
+	}
+
+	ch := make(chan SecurityEvent, securityBuffer)
+	m.security.mu.Lock()
+	m.security.subs[ch] = filter
+	m.security.mu.Unlock()
+
+	return ch
+}
+
+// Unsubscribe stops delivery to a channel returned by Subscribe and closes it
+func (m *Middleware) Unsubscribe(events <-chan SecurityEvent) {
+	m.security.mu.Lock()
+	defer m.security.mu.Unlock()
+
+	for ch := range m.security.subs {
+		if ch == events {
+			delete(m.security.subs, ch)
+			close(ch)
+			return
+		}
+	}
+}
+
+// publishSecurity delivers a security event about r to the subscribers
+func (m *Middleware) publishSecurity(t SecurityEventType, r *http.Request, requestID, reason string) {
+	if shadowed(r.Context()) {
+		return
+	}
+
+	m.security.mu.RLock()
+	defer m.security.mu.RUnlock()
+
+	if len(m.security.subs) == 0 {
+		return
+	}
+
+	clientIP := GetClientIP(r.Context())
+	if clientIP == "" {
+		clientIP = m.clientIP(r)
+	}
+
+	event := SecurityEvent{
+		Type:      t,
+		Time:      m.now(),
+		RequestID: requestID,
+		ClientIP:  clientIP,
+		UserID:    GetUserID(r.Context()),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Reason:    reason,
+	}
+
+	for ch, filter := range m.security.subs {
+		if len(filter) > 0 && !filter[t] {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// rejectSuspicious logs, publishes and answers with 400 a request rejected
+// as an attack attempt, tagging the entry with the event type
+func (m *Middleware) rejectSuspicious(w http.ResponseWriter, r *http.Request, t SecurityEventType, reason string) {
+	r, requestID := m.withRequestID(r)
+
+	m.logRequest(r.Context(), WarnLevel, "Suspicious request rejected", Fields{
+		"app":        m.app,
+		"request_id": requestID,
+		"client_ip":  m.clientIP(r),
+		"method":     r.Method,
+		"path":       r.URL.EscapedPath(),
+		"event":      string(t),
+		"reason":     reason,
+	})
+	m.publishSecurity(t, r, requestID, reason)
+
+	http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+}
@@ -0,0 +1,277 @@
+package puente
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultShutdownTimeout = 30 * time.Second
+
+// ServerConfig configures the listeners run by a Server. Only the public
+// listener is required; every other listener is enabled by its address.
+type ServerConfig struct {
+	// Addr is the public listener address, e.g. ":8443"
+	Addr string
+	// Handler serves the public and Unix socket listeners
+	Handler http.Handler
+	// CertFile and KeyFile enable HTTPS on the public listener
+	CertFile string
+	KeyFile  string
+	// RedirectAddr enables a plaintext listener redirecting to HTTPS
+	RedirectAddr string
+	// UnixSocket enables a listener on the given socket path
+	UnixSocket string
+	// AdminAddr enables a separate admin/metrics listener
+	AdminAddr    string
+	AdminHandler http.Handler
+	// ShutdownTimeout bounds the graceful shutdown, 30 seconds by default
+	ShutdownTimeout time.Duration
+	// SystemdActivation uses the sockets passed by systemd, matched by
+	// FileDescriptorName (public, redirect, unix or admin), instead of
+	// binding them. READY, STOPPING and WATCHDOG notifications are sent
+	// whenever NOTIFY_SOCKET is set, regardless of this option.
+	SystemdActivation bool
+	// ReadinessChecks must all pass for the Ready handler to answer 200
+	ReadinessChecks []Check
+	// DrainDelay keeps serving while reporting not ready once shutdown
+	// starts, giving load balancers time to deregister the instance
+	DrainDelay time.Duration
+	// WatchFile is polled every WatchInterval (10 seconds by default) and
+	// OnReload is called whenever it changes. A failed reload makes the
+	// server not ready until the next successful one.
+	WatchFile     string
+	WatchInterval time.Duration
+	OnReload      func() error
+	// ReadHeaderTimeout, ReadTimeout, WriteTimeout and IdleTimeout are set
+	// on every listener, see http.Server. Connections closed before sending
+	// a complete request header are logged as dropped for slowness; use
+	// Deadlines to adjust the body and write timeouts per route.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// PreflightChecks must all pass before the listeners start, each
+	// within PreflightTimeout (10 seconds by default); see Preflight
+	PreflightChecks  []Check
+	PreflightTimeout time.Duration
+}
+
+// Server runs the public, redirect, Unix socket and admin listeners from a
+// single configuration and shuts them down together
+type Server struct {
+	m         *Middleware
+	cfg       ServerConfig
+	listeners []*listener
+	inherited map[string]net.Listener
+	lifecycle lifecycle
+	conns     connTracker
+	stats     serverStats
+}
+
+// listener is a named http.Server bound to its net.Listener
+type listener struct {
+	name   string
+	server *http.Server
+	ln     net.Listener
+	tls    bool
+}
+
+// NewServer returns a Server for cfg
+func (m *Middleware) NewServer(cfg ServerConfig) *Server {
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	return &Server{m: m, cfg: cfg, stats: serverStats{clock: m.clock}}
+}
+
+// Run starts every configured listener and blocks until ctx is done or a
+// listener fails, then gracefully shuts all of them down
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.Preflight(ctx); err != nil {
+		return err
+	}
+
+	if s.cfg.SystemdActivation {
+		inherited, err := systemdListeners()
+		if err != nil {
+			return err
+		}
+		s.inherited = inherited
+	}
+
+	if err := s.listen(); err != nil {
+		s.close()
+		return err
+	}
+
+	errs := make(chan error, len(s.listeners))
+	var wg sync.WaitGroup
+	for _, l := range s.listeners {
+		wg.Add(1)
+		go func(l *listener) {
+			defer wg.Done()
+			s.m.log(InfoLevel, "Listener started", Fields{
+				"app":      s.m.app,
+				"listener": l.name,
+				"addr":     l.ln.Addr().String(),
+			})
+
+			var err error
+			if l.tls {
+				err = l.server.ServeTLS(l.ln, s.cfg.CertFile, s.cfg.KeyFile)
+			} else {
+				err = l.server.Serve(l.ln)
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}(l)
+	}
+
+	if err := sdNotify("READY=1"); err != nil {
+		s.m.log(WarnLevel, "Failed to notify systemd", Fields{"app": s.m.app, "error": err})
+	}
+	s.lifecycle.setReady(true)
+	s.stats.started = s.m.now()
+	background, stopBackground := context.WithCancel(ctx)
+	go sdWatchdog(background)
+	go s.watch(background)
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+		s.m.log(ErrorLevel, "Listener failed", Fields{"app": s.m.app, "error": err})
+	}
+
+	stopBackground()
+	sdNotify("STOPPING=1")
+	drainStart := s.m.now()
+	s.drain()
+	drained := s.stats.inFlight.Load()
+	s.shutdown()
+	wg.Wait()
+	s.report(drained, s.m.since(drainStart))
+	return err
+}
+
+// listen binds every configured listener
+func (s *Server) listen() error {
+	public := &http.Server{Addr: s.cfg.Addr, Handler: s.cfg.Handler}
+	if err := s.add("public", "tcp", s.cfg.Addr, public, s.cfg.CertFile != ""); err != nil {
+		return err
+	}
+
+	if s.cfg.RedirectAddr != "" {
+		redirect := &http.Server{Addr: s.cfg.RedirectAddr, Handler: redirectHandler(s.cfg.Addr)}
+		if err := s.add("redirect", "tcp", s.cfg.RedirectAddr, redirect, false); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.UnixSocket != "" {
+		// Remove a stale socket left behind by an unclean exit
+		if _, ok := s.inherited["unix"]; !ok {
+			if err := os.Remove(s.cfg.UnixSocket); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		unix := &http.Server{Handler: s.cfg.Handler}
+		if err := s.add("unix", "unix", s.cfg.UnixSocket, unix, false); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.AdminAddr != "" {
+		admin := &http.Server{Addr: s.cfg.AdminAddr, Handler: s.cfg.AdminHandler}
+		if err := s.add("admin", "tcp", s.cfg.AdminAddr, admin, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add binds a listener
+func (s *Server) add(name, network, addr string, server *http.Server, tls bool) error {
+	ln, ok := s.inherited[name]
+	if !ok {
+		var err error
+		if ln, err = net.Listen(network, addr); err != nil {
+			return err
+		}
+	}
+
+	server.ReadHeaderTimeout = s.cfg.ReadHeaderTimeout
+	server.ReadTimeout = s.cfg.ReadTimeout
+	server.WriteTimeout = s.cfg.WriteTimeout
+	server.IdleTimeout = s.cfg.IdleTimeout
+	if name == "public" || name == "unix" {
+		server.Handler = s.stats.wrap(server.Handler)
+	}
+	s.trackConns(name, server)
+
+	s.listeners = append(s.listeners, &listener{
+		name:   name,
+		server: server,
+		ln:     ln,
+		tls:    tls,
+	})
+	return nil
+}
+
+// shutdown gracefully stops every listener within the shutdown timeout
+func (s *Server) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, l := range s.listeners {
+		wg.Add(1)
+		go func(l *listener) {
+			defer wg.Done()
+
+			fields := Fields{"app": s.m.app, "listener": l.name}
+			if err := l.server.Shutdown(ctx); err != nil {
+				fields["error"] = err
+				s.m.log(ErrorLevel, "Listener shutdown failed", fields)
+				l.server.Close()
+				return
+			}
+			s.m.log(InfoLevel, "Listener stopped", fields)
+		}(l)
+	}
+	wg.Wait()
+}
+
+// close releases listeners bound before a startup failure
+func (s *Server) close() {
+	for _, l := range s.listeners {
+		l.ln.Close()
+	}
+}
+
+// redirectHandler redirects plaintext requests to the HTTPS address
+func redirectHandler(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if port != "" && port != "443" {
+				host = net.JoinHostPort(host, port)
+			}
+
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		},
+	)
+}
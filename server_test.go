@@ -0,0 +1,125 @@
+package puente_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// logged returns the fields of the first entry with msg
+func logged(logger *puentetest.Logger, msg string) (puente.Fields, bool) {
+	for _, e := range logger.Entries() {
+		if e.Message == msg {
+			return e.Fields, true
+		}
+	}
+	return nil, false
+}
+
+func TestServerDrainsInFlightRequests(t *testing.T) {
+	clock := puentetest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	logger := &puentetest.Logger{}
+	m := puente.NewWithLogger("app", logger, puente.WithClock(clock))
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	srv := m.NewServer(puente.ServerConfig{
+		Addr:       "127.0.0.1:0",
+		DrainDelay: 5 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := w.(http.Flusher); !ok {
+				t.Error("writer does not implement http.Flusher")
+			}
+			close(entered)
+			<-release
+			io.WriteString(w, "done")
+		}),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	var addr string
+	waitFor(t, "the listener", func() bool {
+		fields, ok := logged(logger, "Listener started")
+		addr, _ = fields["addr"].(string)
+		return ok
+	})
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{string(body), err}
+	}()
+	<-entered
+
+	cancel()
+	waitFor(t, "draining", func() bool {
+		rec := httptest.NewRecorder()
+		srv.Ready().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		_, draining := logged(logger, "Draining")
+		return draining && rec.Code == http.StatusServiceUnavailable
+	})
+
+	// The listener keeps accepting until the drain delay elapses on the clock
+	waitFor(t, "the listener to close", func() bool {
+		clock.Advance(time.Second)
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	})
+
+	close(release)
+	if r := <-response; r.err != nil || r.body != "done" {
+		t.Errorf("in-flight request = %q, %v, want it completed", r.body, r.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v, want nil", err)
+	}
+
+	fields, ok := logged(logger, "Server stopped")
+	if !ok {
+		t.Fatal("shutdown summary not logged")
+	}
+	if got := fields["requests"]; got != int64(1) {
+		t.Errorf("requests = %v, want 1", got)
+	}
+	if got := fields["drained"]; got != int64(1) {
+		t.Errorf("drained = %v, want 1", got)
+	}
+	if got := fields["drain_duration"].(time.Duration); got < 5*time.Second {
+		t.Errorf("drain_duration = %v, want at least the drain delay", got)
+	}
+}
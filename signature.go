@@ -0,0 +1,122 @@
+package puente
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultSignatureHeader = "X-Signature"
+	defaultReplayTolerance = 5 * time.Minute
+	defaultMaxSignedBody   = 1 << 20
+)
+
+// SignatureConfig configures webhook HMAC signature verification
+type SignatureConfig struct {
+	// Secret is the shared HMAC-SHA256 key
+	Secret []byte
+	// Header carries the hex encoded signature, X-Signature by default
+	Header string
+	// Prefix is stripped from the header value, e.g. "sha256="
+	Prefix string
+	// TimestampHeader enables replay protection. When set the signed
+	// payload is "<timestamp>.<body>" and the unix timestamp must be within
+	// Tolerance of the current time.
+	TimestampHeader string
+	// Tolerance is the replay window, 5 minutes by default
+	Tolerance time.Duration
+	// MaxBodyBytes limits the body read for verification, 1 MiB by default
+	MaxBodyBytes int64
+}
+
+// VerifySignature middleware rejects requests whose body HMAC signature
+// does not match the configured secret
+func (m *Middleware) VerifySignature(cfg SignatureConfig) func(http.Handler) http.Handler {
+	if cfg.Header == "" {
+		cfg.Header = defaultSignatureHeader
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = defaultReplayTolerance
+	}
+	if cfg.MaxBodyBytes == 0 {
+		cfg.MaxBodyBytes = defaultMaxSignedBody
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				r, requestID := m.withRequestID(r)
+
+				reserve := cfg.MaxBodyBytes
+				if r.ContentLength >= 0 && r.ContentLength < reserve {
+					reserve = r.ContentLength
+				}
+				if !m.budget.acquire(reserve) {
+					SetLogField(r.Context(), "buffer_skipped", true)
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+				defer m.budget.release(reserve)
+
+				body, err := io.ReadAll(io.LimitReader(r.Body, reserve+1))
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+				if int64(len(body)) > reserve {
+					http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+					return
+				}
+
+				if err := cfg.verify(r, body, m.now()); err != nil {
+					m.authFailure(w, r, requestID, "", err)
+					return
+				}
+
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+// verify checks the request signature and timestamp
+func (cfg SignatureConfig) verify(r *http.Request, body []byte, now time.Time) error {
+	signature := r.Header.Get(cfg.Header)
+	if signature == "" {
+		return errors.New("missing signature")
+	}
+
+	given, err := ParseSignature(signature, cfg.Prefix)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, cfg.Secret)
+	if cfg.TimestampHeader != "" {
+		timestamp := r.Header.Get(cfg.TimestampHeader)
+		signed, err := ParseTimestamp(timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid signature timestamp: %w", err)
+		}
+
+		age := now.Sub(signed)
+		if age > cfg.Tolerance || age < -cfg.Tolerance {
+			return errors.New("signature timestamp outside replay window")
+		}
+
+		mac.Write([]byte(timestamp + "."))
+	}
+	mac.Write(body)
+
+	if !hmac.Equal(given, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
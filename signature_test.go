@@ -0,0 +1,82 @@
+package puente_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+// sign returns the signature of a timestamped payload
+func sign(secret []byte, timestamp, body string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "." + body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignatureReplayWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	secret := []byte("shared secret")
+	body := `{"event":"paid"}`
+
+	tests := []struct {
+		name      string
+		age       time.Duration
+		timestamp string
+		signature func(timestamp string) string
+		status    int
+	}{
+		{"fresh", 0, "", nil, http.StatusOK},
+		{"within window", 4*time.Minute + 59*time.Second, "", nil, http.StatusOK},
+		{"window edge", 5 * time.Minute, "", nil, http.StatusOK},
+		{"replayed", 5*time.Minute + time.Second, "", nil, http.StatusUnauthorized},
+		{"from the future", -6 * time.Minute, "", nil, http.StatusUnauthorized},
+		{"missing timestamp", 0, "-", nil, http.StatusUnauthorized},
+		{"malformed timestamp", 0, "yesterday", nil, http.StatusUnauthorized},
+		{"timestamp not signed", 0, "", func(string) string { return sign(secret, "0", body) }, http.StatusUnauthorized},
+		{"wrong secret", 0, "", func(ts string) string { return sign([]byte("other"), ts, body) }, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := puente.NewWithLogger("app", &puentetest.Logger{}, puente.WithClock(puentetest.NewClock(now)))
+			h := m.VerifySignature(puente.SignatureConfig{
+				Secret:          secret,
+				Prefix:          "sha256=",
+				TimestampHeader: "X-Timestamp",
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			timestamp := strconv.FormatInt(now.Add(-tt.age).Unix(), 10)
+			signature := sign(secret, timestamp, body)
+			if tt.signature != nil {
+				signature = tt.signature(timestamp)
+			}
+			switch tt.timestamp {
+			case "":
+			case "-":
+				timestamp = ""
+			default:
+				timestamp = tt.timestamp
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+			r.Header.Set("X-Signature", signature)
+			if timestamp != "" {
+				r.Header.Set("X-Timestamp", timestamp)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package puente
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// defaultSizeClasses are the upper bounds of the default size classes
+var defaultSizeClasses = []int64{1 << 10, 64 << 10, 1 << 20, 10 << 20, 100 << 20}
+
+// defaultLargePayload is the body size above which a Warn is logged
+const defaultLargePayload = 10 << 20
+
+// SizeConfig configures payload size tracking
+type SizeConfig struct {
+	// Classes are the ascending upper bounds, in bytes, of the size classes:
+	// 1 KB, 64 KB, 1 MB, 10 MB and 100 MB by default. Larger bodies fall in
+	// an extra last class.
+	Classes []int64
+	// WarnAbove logs a "Large payload" Warn for request or response bodies
+	// larger than it, 10 MB by default. Negative disables the warnings.
+	WarnAbove int64
+}
+
+// SizeHistogram counts the request and response bodies of a route per size
+// class, the last count holding the bodies larger than every class
+type SizeHistogram struct {
+	Route     string  `json:"route"`
+	Classes   []int64 `json:"classes"`
+	Requests  []int64 `json:"requests"`
+	Responses []int64 `json:"responses"`
+}
+
+// Sizes tracks the distribution of payload sizes per route
+type Sizes struct {
+	m          *Middleware
+	cfg        SizeConfig
+	mu         sync.Mutex
+	histograms map[string]*SizeHistogram
+}
+
+// NewSizes returns a payload size tracking middleware
+func (m *Middleware) NewSizes(cfg SizeConfig) *Sizes {
+	if len(cfg.Classes) == 0 {
+		cfg.Classes = defaultSizeClasses
+	}
+	if cfg.WarnAbove == 0 {
+		cfg.WarnAbove = defaultLargePayload
+	}
+
+	return &Sizes{
+		m:          m,
+		cfg:        cfg,
+		histograms: map[string]*SizeHistogram{},
+	}
+}
+
+// Track middleware records the request and response body sizes of every
+// request under its route, as resolved for the route log field. Requests
+// count the larger of the declared Content-Length and the bytes read.
+func (s *Sizes) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+
+			wrapped := newResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			route := s.m.Route(r)
+
+			requestBytes := max(r.ContentLength, body.bytes)
+			s.observe(route, requestBytes, wrapped.bytes)
+			s.warn(r, route, "request", requestBytes)
+			s.warn(r, route, "response", wrapped.bytes)
+		},
+	)
+}
+
+// observe adds the body sizes of a request to the histogram of its route
+func (s *Sizes) observe(route string, request, response int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.histograms[route]
+	if !ok {
+		h = &SizeHistogram{
+			Route:     route,
+			Classes:   s.cfg.Classes,
+			Requests:  make([]int64, len(s.cfg.Classes)+1),
+			Responses: make([]int64, len(s.cfg.Classes)+1),
+		}
+		s.histograms[route] = h
+	}
+	h.Requests[s.class(request)]++
+	h.Responses[s.class(response)]++
+}
+
+// class returns the index of the size class of n bytes
+func (s *Sizes) class(n int64) int {
+	return sort.Search(len(s.cfg.Classes), func(i int) bool { return n <= s.cfg.Classes[i] })
+}
+
+// warn logs bodies larger than the configured limit
+func (s *Sizes) warn(r *http.Request, route, direction string, bytes int64) {
+	if s.cfg.WarnAbove < 0 || bytes <= s.cfg.WarnAbove {
+		return
+	}
+
+	fields := Fields{
+		"app":        s.m.app,
+		"request_id": GetRequestID(r.Context()),
+		"method":     r.Method,
+		"path":       r.URL.EscapedPath(),
+		"direction":  direction,
+		"bytes":      bytes,
+		"limit":      s.cfg.WarnAbove,
+	}
+	if route != "" {
+		fields["route"] = route
+	}
+	s.m.logRequest(r.Context(), WarnLevel, "Large payload", fields)
+}
+
+// Histograms returns a copy of the size histograms, sorted by route
+func (s *Sizes) Histograms() []SizeHistogram {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	histograms := make([]SizeHistogram, 0, len(s.histograms))
+	for _, h := range s.histograms {
+		histograms = append(histograms, SizeHistogram{
+			Route:     h.Route,
+			Classes:   h.Classes,
+			Requests:  append([]int64(nil), h.Requests...),
+			Responses: append([]int64(nil), h.Responses...),
+		})
+	}
+	sort.Slice(histograms, func(i, j int) bool { return histograms[i].Route < histograms[j].Route })
+	return histograms
+}
@@ -0,0 +1,68 @@
+package puente
+
+import (
+	"regexp"
+	"strings"
+)
+
+// skipPaths matches the paths excluded from the access log
+type skipPaths struct {
+	exact    map[string]bool
+	prefixes []string
+	patterns []*regexp.Regexp
+}
+
+// WithSkipPaths excludes paths from the access log, e.g. health checks and
+// metrics scrapes. A path ending in "*" matches by prefix, any other path
+// must match exactly. Requests are still served, only the log line is
+// dropped.
+func WithSkipPaths(paths ...string) Option {
+	return func(m *Middleware) {
+		s := m.skipPaths()
+		for _, path := range paths {
+			if strings.HasSuffix(path, "*") {
+				s.prefixes = append(s.prefixes, strings.TrimSuffix(path, "*"))
+				continue
+			}
+			s.exact[path] = true
+		}
+	}
+}
+
+// WithSkipPathPatterns excludes paths matching any of the patterns from the
+// access log
+func WithSkipPathPatterns(patterns ...*regexp.Regexp) Option {
+	return func(m *Middleware) {
+		s := m.skipPaths()
+		s.patterns = append(s.patterns, patterns...)
+	}
+}
+
+// skipPaths returns the skip matcher, creating it on first use
+func (m *Middleware) skipPaths() *skipPaths {
+	if m.skip == nil {
+		m.skip = &skipPaths{exact: map[string]bool{}}
+	}
+	return m.skip
+}
+
+// match reports whether path is excluded from the access log
+func (s *skipPaths) match(path string) bool {
+	if s == nil {
+		return false
+	}
+	if s.exact[path] {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	for _, pattern := range s.patterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package puente
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// slogLogger adapts a slog logger to Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlog returns a middleware instance logging through the standard
+// library structured logger
+func NewSlog(app string, logger *slog.Logger, opts ...Option) *Middleware {
+	return NewWithLogger(app, NewSlogLogger(logger), opts...)
+}
+
+// NewSlogLogger returns a Logger writing to a slog logger
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+// Log implements Logger, emitting the fields as attributes sorted by key
+func (l slogLogger) Log(level Level, msg string, fields Fields) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+
+	l.logger.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+// slogLevel maps a Level to its slog counterpart
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
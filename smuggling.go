@@ -0,0 +1,137 @@
+package puente
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HeaderValidation configures ValidateHeaders
+type HeaderValidation struct {
+	// Hardened adds checks suited to puente being the first hop, where
+	// ambiguous requests are forwarded as is by no one else: underscores
+	// in header names, Connection nominating framing headers and bodies on
+	// GET and HEAD requests are rejected too
+	Hardened bool
+}
+
+// ValidateHeaders middleware rejects with 400 requests whose headers could
+// be framed differently by another hop, the basis of request smuggling and
+// splitting: conflicting or malformed Content-Length and Transfer-Encoding,
+// and header names or values with invalid characters such as CR, LF or
+// NUL. Rejections are logged and published as EventInvalidHeaders.
+//
+// net/http already rejects most of these while parsing and merges obs-fold
+// continuation lines; this catches what reaches handlers through other
+// servers, HTTP/2 translation or synthesized requests.
+func (m *Middleware) ValidateHeaders(cfg HeaderValidation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				reason := invalidHeaders(r)
+				if reason == "" && cfg.Hardened {
+					reason = ambiguousHeaders(r)
+				}
+				if reason != "" {
+					m.rejectSuspicious(w, r, EventInvalidHeaders, reason)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+// invalidHeaders returns why the request headers are invalid, or an empty
+// string
+func invalidHeaders(r *http.Request) string {
+	lengths := r.Header.Values("Content-Length")
+	if len(lengths) > 1 {
+		return "multiple Content-Length"
+	}
+	for _, length := range lengths {
+		if length == "" || strings.Trim(length, "0123456789") != "" {
+			return "invalid Content-Length"
+		}
+	}
+
+	if len(r.Header.Values("Transfer-Encoding")) > 0 || len(r.TransferEncoding) > 0 {
+		if len(lengths) > 0 {
+			return "Content-Length with Transfer-Encoding"
+		}
+		for _, te := range append(r.Header.Values("Transfer-Encoding"), r.TransferEncoding...) {
+			if !strings.EqualFold(strings.TrimSpace(te), "chunked") {
+				return "unsupported Transfer-Encoding"
+			}
+		}
+	}
+
+	for name, values := range r.Header {
+		if !validHeaderName(name) {
+			return "invalid header name"
+		}
+		for _, value := range values {
+			if !validHeaderValue(value) {
+				return "invalid header value"
+			}
+		}
+	}
+	if !validHeaderValue(r.Host) || strings.ContainsAny(r.Host, " \t") {
+		return "invalid Host"
+	}
+	return ""
+}
+
+// ambiguousHeaders returns why the request headers are ambiguous to other
+// hops, or an empty string
+func ambiguousHeaders(r *http.Request) string {
+	for name := range r.Header {
+		if strings.IndexByte(name, '_') >= 0 {
+			return "underscore in header name"
+		}
+	}
+
+	for _, value := range r.Header.Values("Connection") {
+		for _, option := range strings.Split(value, ",") {
+			switch http.CanonicalHeaderKey(strings.TrimSpace(option)) {
+			case "Content-Length", "Transfer-Encoding", "Host":
+				return "framing header nominated by Connection"
+			}
+		}
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if r.ContentLength != 0 || len(r.TransferEncoding) > 0 {
+			return "body on " + r.Method
+		}
+	}
+	return ""
+}
+
+// validHeaderName reports whether name is an RFC 9110 token
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validHeaderValue reports whether value is free of control characters
+// other than horizontal tab
+func validHeaderValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < ' ' && c != '\t' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package puente_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestValidateHeadersFraming(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		header   http.Header
+		te       []string
+		hardened bool
+		reason   string
+	}{
+		{"content-length", http.MethodPost, http.Header{"Content-Length": {"4"}}, nil, false, ""},
+		{"chunked", http.MethodPost, http.Header{"Transfer-Encoding": {"chunked"}}, nil, false, ""},
+		{"content-length and transfer-encoding", http.MethodPost, http.Header{"Content-Length": {"4"}, "Transfer-Encoding": {"chunked"}}, nil, false, "Content-Length with Transfer-Encoding"},
+		{"content-length and parsed transfer-encoding", http.MethodPost, http.Header{"Content-Length": {"4"}}, []string{"chunked"}, false, "Content-Length with Transfer-Encoding"},
+		{"multiple content-length", http.MethodPost, http.Header{"Content-Length": {"4", "5"}}, nil, false, "multiple Content-Length"},
+		{"signed content-length", http.MethodPost, http.Header{"Content-Length": {"+4"}}, nil, false, "invalid Content-Length"},
+		{"obfuscated transfer-encoding", http.MethodPost, http.Header{"Transfer-Encoding": {"xchunked"}}, nil, false, "unsupported Transfer-Encoding"},
+		{"header value with CRLF", http.MethodGet, http.Header{"X-Note": {"a\r\nX-Injected: 1"}}, nil, false, "invalid header value"},
+		{"header name with space", http.MethodGet, http.Header{"X Note": {"a"}}, nil, false, "invalid header name"},
+		{"underscore tolerated", http.MethodGet, http.Header{"X_Note": {"a"}}, nil, false, ""},
+		{"underscore hardened", http.MethodGet, http.Header{"X_Note": {"a"}}, nil, true, "underscore in header name"},
+		{"connection nominates content-length", http.MethodPost, http.Header{"Connection": {"keep-alive, content-length"}}, nil, true, "framing header nominated by Connection"},
+		{"body on GET", http.MethodGet, http.Header{"Transfer-Encoding": {"chunked"}}, []string{"chunked"}, true, "body on GET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := puente.NewWithLogger("app", &puentetest.Logger{})
+			events := m.Subscribe(puente.EventInvalidHeaders)
+			defer m.Unsubscribe(events)
+
+			h := m.ValidateHeaders(puente.HeaderValidation{Hardened: tt.hardened})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(tt.method, "/", strings.NewReader(""))
+			r.ContentLength = 0
+			r.Header = tt.header
+			r.TransferEncoding = tt.te
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if tt.reason == "" {
+				if rec.Code != http.StatusOK {
+					t.Errorf("status = %d, want 200", rec.Code)
+				}
+				return
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", rec.Code)
+			}
+			select {
+			case event := <-events:
+				if event.Reason != tt.reason {
+					t.Errorf("reason = %q, want %q", event.Reason, tt.reason)
+				}
+			default:
+				t.Error("no security event published")
+			}
+		})
+	}
+}
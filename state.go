@@ -0,0 +1,63 @@
+package puente
+
+import (
+	"context"
+	"sync"
+)
+
+// stateKey is the context key holding the per-request state
+const stateKey contextKey = "puente_state"
+
+// requestState collects fields set by inner middleware and handlers so the
+// Logging middleware can include them in the access log
+type requestState struct {
+	mu     sync.Mutex
+	fields Fields
+	// stack records the stack with SetError
+	stack bool
+}
+
+// newState returns a context carrying a fresh request state
+func newState(ctx context.Context) (context.Context, *requestState) {
+	s := &requestState{fields: Fields{}}
+	return context.WithValue(ctx, stateKey, s), s
+}
+
+// getState returns the request state, or nil outside the Logging middleware
+func getState(ctx context.Context) *requestState {
+	s, _ := ctx.Value(stateKey).(*requestState)
+	return s
+}
+
+// SetLogField adds a field to the access log of the current request. It is
+// safe for concurrent use and may be called until the handler returns,
+// including from deferred functions; the last write of a key wins. Fields
+// set by the Logging middleware itself cannot be overridden.
+func SetLogField(ctx context.Context, key string, value interface{}) {
+	if s := getState(ctx); s != nil {
+		s.mu.Lock()
+		s.fields[key] = value
+		s.mu.Unlock()
+	}
+}
+
+// GetLogFields returns a copy of the fields added to the access log of the
+// current request so far, or nil outside the Logging middleware
+func GetLogFields(ctx context.Context) Fields {
+	if s := getState(ctx); s != nil {
+		return s.snapshot()
+	}
+	return nil
+}
+
+// snapshot returns a copy of the recorded fields
+func (s *requestState) snapshot() Fields {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := make(Fields, len(s.fields))
+	for k, v := range s.fields {
+		fields[k] = v
+	}
+	return fields
+}
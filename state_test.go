@@ -0,0 +1,85 @@
+package puente_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/javiertlopez/puente"
+	"github.com/javiertlopez/puente/puentetest"
+)
+
+func TestSetLogFieldLastWriteWins(t *testing.T) {
+	logger := &puentetest.Logger{}
+	m := puente.NewWithLogger("app", logger)
+
+	late := make(chan struct{})
+	done := make(chan struct{})
+	h := m.Logging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		defer puente.SetLogField(ctx, "outcome", "deferred")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				puente.SetLogField(ctx, "outcome", fmt.Sprintf("worker %d", i))
+				puente.SetLogField(ctx, "records_processed", i)
+			}(i)
+		}
+		wg.Wait()
+		puente.SetLogField(ctx, "records_processed", 50)
+
+		// Writes after the handler returns race with the access log
+		go func() {
+			defer close(done)
+			<-late
+			for i := 0; i < 50; i++ {
+				puente.SetLogField(ctx, "outcome", "too late")
+			}
+		}()
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	close(late)
+	<-done
+
+	entries := logger.AccessLog()
+	if len(entries) != 1 {
+		t.Fatalf("got %d access log entries, want 1", len(entries))
+	}
+	fields := entries[0].Fields
+	if got := fields["outcome"]; got != "deferred" {
+		t.Errorf("outcome = %v, want the deferred write", got)
+	}
+	if got := fields["records_processed"]; got != 50 {
+		t.Errorf("records_processed = %v, want the last write 50", got)
+	}
+}
+
+func TestSetLogFieldCannotOverrideCoreFields(t *testing.T) {
+	logger := &puentetest.Logger{}
+	m := puente.NewWithLogger("app", logger)
+
+	h := m.Logging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		puente.SetLogField(r.Context(), "status", 999)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := logger.AccessLog()[0].Fields["status"]; got != http.StatusTeapot {
+		t.Errorf("status = %v, want %d", got, http.StatusTeapot)
+	}
+}
+
+func TestSetLogFieldOutsideLogging(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	puente.SetLogField(r.Context(), "key", "value")
+
+	if fields := puente.GetLogFields(r.Context()); fields != nil {
+		t.Errorf("GetLogFields = %v outside Logging, want nil", fields)
+	}
+}
@@ -0,0 +1,166 @@
+package puente
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultStructuredDataID uses the example enterprise number of RFC 5612
+const defaultStructuredDataID = "fields@32473"
+
+// SyslogConfig configures an RFC 5424 syslog Logger
+type SyslogConfig struct {
+	// App is the APP-NAME, "puente" by default
+	App string
+	// Host is the HOSTNAME, the host name by default
+	Host string
+	// Facility is the syslog facility, 16 (local0) by default
+	Facility int
+	// StructuredDataID names the element holding the fields, fields@32473
+	// by default
+	StructuredDataID string
+	// OnError is called when a message cannot be sent
+	OnError func(err error)
+	// Clock timestamps the messages, the wall clock by default
+	Clock Clock
+}
+
+// syslogLogger writes entries as RFC 5424 messages
+type syslogLogger struct {
+	cfg      SyslogConfig
+	pid      string
+	endpoint *endpoint
+}
+
+// DialSyslog returns a Logger sending entries as RFC 5424 syslog messages
+// to a UDP or TCP collector. TCP messages use octet counting framing. The
+// fields are sent as structured data parameters, and access log entries get
+// "METHOD path status" as message.
+func DialSyslog(network, addr string, cfg SyslogConfig) (Logger, error) {
+	if cfg.App == "" {
+		cfg.App = "puente"
+	}
+	if cfg.Host == "" {
+		cfg.Host, _ = os.Hostname()
+	}
+	if cfg.Facility == 0 {
+		cfg.Facility = 16
+	}
+	if cfg.StructuredDataID == "" {
+		cfg.StructuredDataID = defaultStructuredDataID
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = realClock{}
+	}
+
+	frame := func(b, msg []byte) []byte {
+		b = strconv.AppendInt(b, int64(len(msg)), 10)
+		return append(append(b, ' '), msg...)
+	}
+	e, err := dialEndpoint(network, addr, frame, cfg.OnError)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogLogger{cfg: cfg, pid: strconv.Itoa(os.Getpid()), endpoint: e}, nil
+}
+
+// Log implements Logger
+func (l *syslogLogger) Log(level Level, msg string, fields Fields) {
+	b := append([]byte(nil), '<')
+	b = strconv.AppendInt(b, int64(l.cfg.Facility*8+syslogSeverity(level)), 10)
+	b = append(b, ">1 "...)
+	b = l.cfg.Clock.Now().UTC().AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	b = append(b, ' ')
+	b = append(b, syslogHeader(l.cfg.Host, 255)...)
+	b = append(b, ' ')
+	b = append(b, syslogHeader(l.cfg.App, 48)...)
+	b = append(b, ' ')
+	b = append(b, l.pid...)
+	b = append(b, " - "...)
+
+	if len(fields) == 0 {
+		b = append(b, '-')
+	} else {
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b = append(b, '[')
+		b = append(b, l.cfg.StructuredDataID...)
+		for _, k := range keys {
+			b = append(b, ' ')
+			b = append(b, syslogParamName(k)...)
+			b = append(b, '=', '"')
+			b = appendSyslogParamValue(b, fieldString(fields[k]))
+			b = append(b, '"')
+		}
+		b = append(b, ']')
+	}
+
+	b = append(b, ' ')
+	b = append(b, shortMessage(msg, fields)...)
+	l.endpoint.send(b)
+}
+
+// syslogSeverity maps a level to a syslog severity
+func syslogSeverity(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 7
+	case WarnLevel:
+		return 4
+	case ErrorLevel:
+		return 3
+	}
+	return 6
+}
+
+// syslogHeader returns a header field limited to printable ASCII, or the
+// nil value "-"
+func syslogHeader(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// syslogParamName returns a valid SD-NAME: printable ASCII except '=', ' ',
+// ']' and '"', at most 32 characters
+func syslogParamName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// appendSyslogParamValue escapes '"', '\' and ']' in a PARAM-VALUE
+func appendSyslogParamValue(b []byte, value string) []byte {
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\', ']':
+			b = append(b, '\\', c)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
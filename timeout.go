@@ -0,0 +1,63 @@
+package puente
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DeadlineError is the cancellation cause recorded by the Timeout
+// middleware, so deadline exceeded entries name who set the deadline
+type DeadlineError struct {
+	Middleware string
+	Timeout    time.Duration
+}
+
+// Error implements error
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("%s middleware deadline of %s exceeded", e.Middleware, e.Timeout)
+}
+
+// Unwrap makes errors.Is(err, context.DeadlineExceeded) hold
+func (e *DeadlineError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Timeout middleware bounds the request context to d. When the deadline is
+// hit the access log gets context_error and context_cause fields.
+func (m *Middleware) Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				cause := &DeadlineError{Middleware: "Timeout", Timeout: d}
+				ctx, cancel := context.WithTimeoutCause(r.Context(), d, cause)
+				defer cancel()
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				recordContextError(ctx)
+			},
+		)
+	}
+}
+
+// recordContextError adds the context error and its cause to the access
+// log unless an inner middleware already recorded them
+func recordContextError(ctx context.Context) {
+	err := ctx.Err()
+	s := getState(ctx)
+	if err == nil || s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.fields["context_error"]; ok {
+		return
+	}
+	s.fields["context_error"] = err
+	if cause := context.Cause(ctx); cause != err {
+		s.fields["context_cause"] = cause
+	}
+}
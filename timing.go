@@ -0,0 +1,59 @@
+package puente
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// addLogDuration accumulates d into the duration field key of the access log
+func addLogDuration(ctx context.Context, key string, d time.Duration) {
+	if s := getState(ctx); s != nil {
+		s.mu.Lock()
+		total, _ := s.fields[key].(time.Duration)
+		s.fields[key] = total + d
+		s.mu.Unlock()
+	}
+}
+
+// HandlerTiming marks next as the application handler. Its time is logged
+// as handler_duration and the remainder of the request as
+// middleware_duration, attributing latency to puente or the application.
+func (m *Middleware) HandlerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			start := m.now()
+			next.ServeHTTP(w, r)
+			addLogDuration(r.Context(), "handler_duration", m.since(start))
+		},
+	)
+}
+
+// timingTransport records the time spent waiting on upstream calls
+type timingTransport struct {
+	m    *Middleware
+	base http.RoundTripper
+}
+
+// Transport wraps base (http.DefaultTransport when nil) so outgoing calls
+// made with the incoming request context are logged as upstream_duration
+// and, with WithTracePropagation, carry the trace headers of the request
+func (m *Middleware) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return timingTransport{m: m, base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if trace, ok := GetTraceContext(req.Context()); ok && !hasTraceHeaders(req.Header) {
+		req = req.Clone(req.Context())
+		t.m.setTraceHeaders(req.Header, trace)
+	}
+
+	start := t.m.now()
+	resp, err := t.base.RoundTrip(req)
+	addLogDuration(req.Context(), "upstream_duration", t.m.since(start))
+	return resp, err
+}
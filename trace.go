@@ -0,0 +1,199 @@
+package puente
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TracePropagation selects the trace header formats read and propagated
+type TracePropagation uint
+
+// Trace header formats
+const (
+	// PropagateW3C reads and writes W3C Trace Context traceparent and
+	// tracestate headers
+	PropagateW3C TracePropagation = 1 << iota
+	// PropagateB3 reads and writes Zipkin X-B3-TraceId, X-B3-SpanId,
+	// X-B3-ParentSpanId and X-B3-Sampled headers
+	PropagateB3
+)
+
+// traceKey is the context key holding the TraceContext
+const traceKey contextKey = "trace"
+
+// TraceContext identifies the span of a request within a distributed trace
+type TraceContext struct {
+	// TraceID is the 32 hex digit trace ID
+	TraceID string
+	// SpanID is the 16 hex digit ID of the span of this service
+	SpanID string
+	// ParentID is the span ID of the caller, empty when the trace started here
+	ParentID string
+	// Sampled is the caller's sampling decision
+	Sampled bool
+	// State is the vendor specific tracestate, passed through unchanged
+	State string
+}
+
+// Traceparent returns the W3C traceparent header value of the span
+func (t TraceContext) Traceparent() string {
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + flags
+}
+
+// WithTracePropagation makes the Logging middleware continue the trace of
+// incoming requests, or start one when absent, logging trace_id and
+// span_id. The request is forwarded with the headers of its own span. When
+// several formats are selected, W3C headers are read first and all formats
+// are written.
+func WithTracePropagation(formats TracePropagation) Option {
+	return func(m *Middleware) {
+		m.tracing = formats
+	}
+}
+
+// GetTraceContext returns the trace context stored in the context
+func GetTraceContext(ctx context.Context) (TraceContext, bool) {
+	t, ok := ctx.Value(traceKey).(TraceContext)
+	return t, ok
+}
+
+// ContextWithTrace returns a copy of ctx carrying t, for tracing
+// integrations such as oteltrace bridging their span into GetTraceID,
+// GetSpanID and GetLogger
+func ContextWithTrace(ctx context.Context, t TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey, t)
+}
+
+// GetTraceID returns the trace ID stored in the context
+func GetTraceID(ctx context.Context) string {
+	t, _ := GetTraceContext(ctx)
+	return t.TraceID
+}
+
+// GetSpanID returns the span ID of this service stored in the context
+func GetSpanID(ctx context.Context) string {
+	t, _ := GetTraceContext(ctx)
+	return t.SpanID
+}
+
+// withTrace resolves the trace context of r, starting a new span, and
+// returns the request carrying it with its propagation headers replaced
+func (m *Middleware) withTrace(r *http.Request) (*http.Request, TraceContext) {
+	if t, ok := GetTraceContext(r.Context()); ok {
+		return r, t
+	}
+
+	var t TraceContext
+	ok := false
+	if m.tracing&PropagateW3C != 0 {
+		var err error
+		t, err = ParseTraceparent(r.Header.Get("Traceparent"))
+		ok = err == nil
+		if ok {
+			t.State = r.Header.Get("Tracestate")
+		}
+	}
+	if !ok && m.tracing&PropagateB3 != 0 {
+		t, ok = parseB3(r.Header)
+	}
+
+	if ok {
+		t.ParentID = t.SpanID
+	} else {
+		t = TraceContext{TraceID: randomHex(16), Sampled: true}
+	}
+	t.SpanID = randomHex(8)
+
+	r = r.WithContext(ContextWithTrace(r.Context(), t))
+	r.Header = r.Header.Clone()
+	m.setTraceHeaders(r.Header, t)
+	return r, t
+}
+
+// setTraceHeaders writes the propagation headers of t
+func (m *Middleware) setTraceHeaders(h http.Header, t TraceContext) {
+	if m.tracing&PropagateW3C != 0 {
+		h.Set("Traceparent", t.Traceparent())
+		h.Del("Tracestate")
+		if t.State != "" {
+			h.Set("Tracestate", t.State)
+		}
+	}
+	if m.tracing&PropagateB3 != 0 {
+		h.Set("X-B3-TraceId", t.TraceID)
+		h.Set("X-B3-SpanId", t.SpanID)
+		h.Del("X-B3-ParentSpanId")
+		if t.ParentID != "" {
+			h.Set("X-B3-ParentSpanId", t.ParentID)
+		}
+		h.Del("X-B3-Flags")
+		sampled := "0"
+		if t.Sampled {
+			sampled = "1"
+		}
+		h.Set("X-B3-Sampled", sampled)
+	}
+}
+
+// hasTraceHeaders reports whether h already carries propagation headers
+func hasTraceHeaders(h http.Header) bool {
+	return h.Get("Traceparent") != "" || h.Get("X-B3-TraceId") != ""
+}
+
+// parseB3 parses Zipkin multi-header B3 propagation. 64-bit trace IDs are
+// left padded to 128 bits.
+func parseB3(h http.Header) (TraceContext, bool) {
+	traceID := strings.ToLower(strings.TrimSpace(h.Get("X-B3-TraceId")))
+	spanID := strings.ToLower(strings.TrimSpace(h.Get("X-B3-SpanId")))
+
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if len(traceID) != 32 || len(spanID) != 16 || !isLowerHex(traceID) || !isLowerHex(spanID) ||
+		allZero(traceID) || allZero(spanID) {
+		return TraceContext{}, false
+	}
+
+	// Without a sampling decision the request is sampled, as when starting
+	// a trace
+	sampled := true
+	switch strings.ToLower(h.Get("X-B3-Sampled")) {
+	case "0", "false":
+		sampled = false
+	}
+	if h.Get("X-B3-Flags") == "1" {
+		sampled = true
+	}
+
+	return TraceContext{TraceID: traceID, SpanID: spanID, Sampled: sampled}, true
+}
+
+// isLowerHex reports whether s only holds lowercase hex digits
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// allZero reports whether s only holds zeros, an invalid trace or span ID
+func allZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// randomHex returns n random bytes hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
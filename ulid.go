@@ -0,0 +1,93 @@
+package puente
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// crockford is the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidState keeps the last ULID so IDs generated within the same
+// millisecond stay ordered
+var ulidState struct {
+	mu      sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+// ULID returns a Universally Unique Lexicographically Sortable Identifier:
+// a millisecond timestamp followed by 80 random bits, as 26 Crockford
+// base32 characters. IDs generated in the same millisecond are monotonic;
+// should the entropy overflow, the timestamp moves to the next
+// millisecond.
+func ULID() string {
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidState.mu.Lock()
+	if ms <= ulidState.ms {
+		// Same millisecond, or the clock went back: increment the entropy
+		ms = ulidState.ms
+		if !incrementEntropy() {
+			ms++
+			ulidState.ms = ms
+			randomEntropy()
+		}
+	} else {
+		ulidState.ms = ms
+		randomEntropy()
+	}
+	var b [16]byte
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	copy(b[6:], ulidState.entropy[:])
+	ulidState.mu.Unlock()
+
+	return encodeULID(b)
+}
+
+// incrementEntropy adds one to the entropy, reporting false when it
+// wrapped around to zero
+func incrementEntropy() bool {
+	for i := len(ulidState.entropy) - 1; i >= 0; i-- {
+		if ulidState.entropy[i]++; ulidState.entropy[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// randomEntropy draws fresh entropy. crypto/rand only fails when the
+// system source is unavailable; the previous entropy is then kept, which
+// still orders IDs as the timestamp grew, and incremented to stay unique
+// within the process.
+func randomEntropy() {
+	var entropy [10]byte
+	if _, err := rand.Read(entropy[:]); err != nil {
+		incrementEntropy()
+		return
+	}
+	ulidState.entropy = entropy
+}
+
+// encodeULID encodes 128 bits as 26 base32 characters, the first one
+// holding the top 3 bits
+func encodeULID(b [16]byte) string {
+	var out [26]byte
+	// Walk the bits from the least significant end, 5 at a time
+	var acc uint16
+	bits := 0
+	pos := len(out) - 1
+	for i := len(b) - 1; i >= 0; i-- {
+		acc |= uint16(b[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			out[pos] = crockford[acc&0x1f]
+			pos--
+			acc >>= 5
+			bits -= 5
+		}
+	}
+	out[0] = crockford[acc&0x1f]
+	return string(out[:])
+}
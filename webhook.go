@@ -0,0 +1,173 @@
+package puente
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWebhookAttempts = 5
+	defaultWebhookBackoff  = time.Second
+	defaultWebhookTimeout  = 10 * time.Second
+)
+
+// Delivery is an outbound webhook delivery
+type Delivery struct {
+	ID        string
+	RequestID string
+	URL       string
+	Event     string
+	Payload   []byte
+	Attempts  int
+}
+
+// DispatcherConfig configures outbound webhook delivery
+type DispatcherConfig struct {
+	// Secret signs deliveries the same way VerifySignature expects them,
+	// with X-Signature "sha256=<hex>" over "<timestamp>.<body>" and the unix
+	// timestamp in X-Timestamp
+	Secret []byte
+	// Client performs the deliveries, a client with a 10s timeout by default
+	Client *http.Client
+	// MaxAttempts before a delivery is dead-lettered, 5 by default
+	MaxAttempts int
+	// Backoff is the initial retry delay, doubled after every attempt
+	Backoff time.Duration
+	// DeadLetter receives deliveries that exhausted their attempts or were
+	// permanently rejected
+	DeadLetter func(delivery Delivery, err error)
+}
+
+// Dispatcher sends signed webhooks in the background
+type Dispatcher struct {
+	m   *Middleware
+	cfg DispatcherConfig
+	wg  sync.WaitGroup
+}
+
+// NewDispatcher returns an outbound webhook dispatcher
+func (m *Middleware) NewDispatcher(cfg DispatcherConfig) *Dispatcher {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = defaultWebhookAttempts
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = defaultWebhookBackoff
+	}
+
+	return &Dispatcher{m: m, cfg: cfg}
+}
+
+// Dispatch JSON encodes payload and delivers it to url in the background.
+// The request ID of ctx is propagated so delivery logs share the
+// correlation of the triggering request.
+func (d *Dispatcher) Dispatch(ctx context.Context, url, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	delivery := Delivery{
+		ID:        newRequestID(),
+		RequestID: GetRequestID(ctx),
+		URL:       url,
+		Event:     event,
+		Payload:   body,
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(delivery)
+	}()
+
+	return nil
+}
+
+// Wait blocks until all pending deliveries finish
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// deliver attempts the delivery with exponential backoff
+func (d *Dispatcher) deliver(delivery Delivery) {
+	backoff := d.cfg.Backoff
+
+	for {
+		delivery.Attempts++
+		status, err := d.send(delivery)
+
+		fields := Fields{
+			"app":         d.m.app,
+			"request_id":  delivery.RequestID,
+			"delivery_id": delivery.ID,
+			"event":       delivery.Event,
+			"url":         delivery.URL,
+			"attempt":     delivery.Attempts,
+			"status":      status,
+		}
+		if err == nil {
+			d.m.log(InfoLevel, "Webhook delivered", fields)
+			return
+		}
+
+		fields["error"] = err
+
+		retryable := status == 0 || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+		if !retryable || delivery.Attempts >= d.cfg.MaxAttempts {
+			d.m.log(ErrorLevel, "Webhook dead-lettered", fields)
+			if d.cfg.DeadLetter != nil {
+				d.cfg.DeadLetter(delivery, err)
+			}
+			return
+		}
+
+		d.m.log(WarnLevel, "Webhook delivery failed, retrying", fields)
+		sleep(d.m.clock, backoff)
+		backoff *= 2
+	}
+}
+
+// send performs a single signed delivery attempt
+func (d *Dispatcher) send(delivery Delivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(d.m.now().Unix(), 10)
+	mac := hmac.New(sha256.New, d.cfg.Secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(delivery.Payload)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(defaultSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-ID", delivery.ID)
+	if delivery.RequestID != "" {
+		req.Header.Set("X-Request-ID", delivery.RequestID)
+	}
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
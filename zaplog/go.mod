@@ -0,0 +1,16 @@
+module github.com/javiertlopez/puente/zaplog
+
+go 1.23
+
+require (
+	github.com/javiertlopez/puente v0.0.0-00010101000000-000000000000
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	github.com/sirupsen/logrus v1.8.1 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037 // indirect
+)
+
+replace github.com/javiertlopez/puente => ../
@@ -0,0 +1,68 @@
+// Package zaplog adapts zap loggers to puente.Logger. It is a module of its
+// own, so applications not using zap do not depend on it.
+package zaplog
+
+import (
+	"time"
+
+	"github.com/javiertlopez/puente"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logger adapts a zap logger to puente.Logger
+type logger struct {
+	logger *zap.Logger
+}
+
+// New returns a puente.Logger writing to l
+func New(l *zap.Logger) puente.Logger {
+	return logger{logger: l}
+}
+
+// Log implements puente.Logger, using strongly typed zap fields for the
+// common value types to avoid reflection
+func (l logger) Log(level puente.Level, msg string, fields puente.Fields) {
+	ce := l.logger.Check(zapLevel(level), msg)
+	if ce == nil {
+		return
+	}
+
+	zapFields := make([]zap.Field, 0, len(fields))
+	for k, v := range fields {
+		zapFields = append(zapFields, field(k, v))
+	}
+	ce.Write(zapFields...)
+}
+
+// field converts a single value to a zap field
+func field(key string, value interface{}) zap.Field {
+	switch v := value.(type) {
+	case string:
+		return zap.String(key, v)
+	case int:
+		return zap.Int(key, v)
+	case int64:
+		return zap.Int64(key, v)
+	case bool:
+		return zap.Bool(key, v)
+	case time.Duration:
+		return zap.Duration(key, v)
+	case error:
+		return zap.NamedError(key, v)
+	}
+	return zap.Any(key, value)
+}
+
+// zapLevel maps a puente level to its zap counterpart
+func zapLevel(level puente.Level) zapcore.Level {
+	switch level {
+	case puente.DebugLevel:
+		return zapcore.DebugLevel
+	case puente.WarnLevel:
+		return zapcore.WarnLevel
+	case puente.ErrorLevel:
+		return zapcore.ErrorLevel
+	}
+	return zapcore.InfoLevel
+}